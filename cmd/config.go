@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/guessi/cloudtrail-cli/pkg/accounts"
+	"github.com/guessi/cloudtrail-cli/pkg/config"
+	"github.com/guessi/cloudtrail-cli/pkg/geoip"
+	"github.com/guessi/cloudtrail-cli/pkg/iprange"
+	"github.com/guessi/cloudtrail-cli/pkg/redact"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/guessi/cloudtrail-cli/pkg/utils"
+	"github.com/urfave/cli/v3"
+)
+
+// loadConfig reads the file given by --config, or the default one when present
+func loadConfig(c *cli.Command) (*config.Config, string, error) {
+	if path := c.String("config"); path != "" {
+		cfg, err := config.Load(path, true)
+		return cfg, path, err
+	}
+
+	path, err := config.DefaultPath()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.Load(path, false)
+	return cfg, path, err
+}
+
+// stringSetting returns the flag value when set, otherwise the first non-empty
+// value from the configuration, otherwise the flag default
+func stringSetting(c *cli.Command, name string, values ...string) string {
+	if !c.IsSet(name) {
+		for _, value := range values {
+			if value != "" {
+				return value
+			}
+		}
+	}
+	return c.String(name)
+}
+
+// intSetting is the integer counterpart of stringSetting
+func intSetting(c *cli.Command, name string, values ...int) int {
+	if !c.IsSet(name) {
+		for _, value := range values {
+			if value > 0 {
+				return value
+			}
+		}
+	}
+	return c.Int(name)
+}
+
+// timeSetting is the timestamp counterpart of stringSetting
+func timeSetting(c *cli.Command, name string, values ...time.Time) time.Time {
+	if !c.IsSet(name) {
+		for _, value := range values {
+			if !value.IsZero() {
+				return value
+			}
+		}
+	}
+	return c.Timestamp(name)
+}
+
+// columnsSetting returns the --columns list, or the configured one
+func columnsSetting(c *cli.Command, values ...[]string) []string {
+	if !c.IsSet("columns") {
+		for _, value := range values {
+			if len(value) > 0 {
+				return value
+			}
+		}
+	}
+	if c.String("columns") == "" {
+		return nil
+	}
+	return strings.Split(c.String("columns"), ",")
+}
+
+// newRedactor returns the redactor of the run, or nil unless --redact or the
+// configuration file enables redaction
+func newRedactor(c *cli.Command, cfg *config.Config) *redact.Redactor {
+	enabled := c.Bool("redact")
+	if !c.IsSet("redact") {
+		enabled = cfg.Redact.Enabled
+	}
+	if !enabled {
+		return nil
+	}
+
+	rules := redact.DefaultRules()
+	if cfg.Redact.AccountIds != nil {
+		rules.AccountIds = *cfg.Redact.AccountIds
+	}
+	if cfg.Redact.AccessKeyIds != nil {
+		rules.AccessKeyIds = *cfg.Redact.AccessKeyIds
+	}
+	if cfg.Redact.IPAddresses != nil {
+		rules.IPAddresses = *cfg.Redact.IPAddresses
+	}
+	rules.RequestParameters = append(append([]string{}, rules.RequestParameters...), cfg.Redact.RequestParameters...)
+	return redact.New(rules)
+}
+
+// groupBySetting returns the --group-by fields, or the ones of the query
+func groupBySetting(c *cli.Command, q *config.Query) []string {
+	if !c.IsSet("group-by") {
+		return q.GroupBy
+	}
+	if c.String("group-by") == "" {
+		return nil
+	}
+	return strings.Split(c.String("group-by"), ",")
+}
+
+// openGeoIP loads the MaxMind databases given on the command line or in the
+// configuration file, or returns nil when there are none
+func openGeoIP(c *cli.Command, cfg *config.Config) (*geoip.Resolver, error) {
+	return geoip.Open(stringSetting(c, "geoip-db", cfg.GeoIPDatabase), stringSetting(c, "asn-db", cfg.ASNDatabase))
+}
+
+// ipLabelsSetting returns the --ip-labels files, or the configured ones
+func ipLabelsSetting(c *cli.Command, cfg *config.Config) []string {
+	if !c.IsSet("ip-labels") {
+		return cfg.IPLabels
+	}
+	return c.StringSlice("ip-labels")
+}
+
+// openIPTags loads the AWS IP ranges and label files given on the command line
+// or in the configuration file, or returns nil when there are none
+func openIPTags(c *cli.Command, cfg *config.Config) (*iprange.Tagger, error) {
+	return iprange.New(stringSetting(c, "ip-ranges", cfg.IPRanges), ipLabelsSetting(c, cfg))
+}
+
+// openAccounts loads the account aliases of the --accounts-file export and of
+// the configuration file, the latter taking precedence
+func openAccounts(c *cli.Command, cfg *config.Config) (*accounts.Aliases, error) {
+	var exported map[string]string
+	if path := stringSetting(c, "accounts-file", cfg.AccountsFile); path != "" {
+		names, err := accounts.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		exported = names
+	}
+	return accounts.New(exported, cfg.Accounts)
+}
+
+// buildCloudTrailCliInput merges flags, an optional named query and the
+// configuration file, in decreasing order of precedence
+func buildCloudTrailCliInput(c *cli.Command, cfg *config.Config, q *config.Query) types.CloudTrailCliInput {
+	if q == nil {
+		q = &config.Query{}
+	}
+
+	isReadOnlyFlagSet := c.IsSet("read-only")
+	readOnly := c.Bool("read-only")
+	if !isReadOnlyFlagSet && q.ReadOnly != nil {
+		isReadOnlyFlagSet = true
+		readOnly = *q.ReadOnly
+	}
+
+	// A relative window ends now
+	var since time.Time
+	if q.Since > 0 {
+		since = time.Now().Add(-q.Since)
+	}
+
+	errorOnly := c.Bool("error-only")
+	if !c.IsSet("error-only") {
+		errorOnly = q.ErrorOnly
+	}
+
+	excludeKnownIPs := c.Bool("exclude-known-ips")
+	if !c.IsSet("exclude-known-ips") {
+		excludeKnownIPs = q.ExcludeKnownIPs
+	}
+
+	insights := c.Bool("insights")
+	if !c.IsSet("insights") {
+		insights = q.Insights
+	}
+
+	truncateUserName := c.Bool("truncate-user-name")
+	if !c.IsSet("truncate-user-name") {
+		truncateUserName = cfg.Truncate.UserName > 0
+	}
+	truncateUserAgent := c.Bool("truncate-user-agent")
+	if !c.IsSet("truncate-user-agent") {
+		truncateUserAgent = cfg.Truncate.UserAgent > 0
+	}
+
+	return types.CloudTrailCliInput{
+		Profile:           stringSetting(c, "profile", cfg.Profile),
+		Region:            stringSetting(c, "region", cfg.Region),
+		EndpointUrl:       c.String("endpoint-url"),
+		StartTime:         timeSetting(c, "start-time", q.StartTime, since),
+		EndTime:           timeSetting(c, "end-time", q.EndTime),
+		EventId:           stringSetting(c, "event-id", q.EventId),
+		EventName:         stringSetting(c, "event-name", q.EventName),
+		UserName:          stringSetting(c, "user-name", q.UserName),
+		ResourceName:      stringSetting(c, "resource-name", q.ResourceName),
+		ResourceType:      stringSetting(c, "resource-type", q.ResourceType),
+		EventSource:       stringSetting(c, "event-source", q.EventSource),
+		AccessKeyId:       stringSetting(c, "access-key-id", q.AccessKeyId),
+		IsReadOnlyFlagSet: isReadOnlyFlagSet,
+		ReadOnly:          readOnly,
+		MaxResults:        intSetting(c, "max-results", q.MaxResults, cfg.MaxResults),
+		ErrorOnly:         errorOnly,
+		ExcludeKnownIPs:   excludeKnownIPs,
+		Insights:          insights,
+		TruncateUserName:  truncateUserName,
+		TruncateUserAgent: truncateUserAgent,
+		UserNameLength:    cfg.Truncate.UserName,
+		UserAgentLength:   cfg.Truncate.UserAgent,
+		Output:            stringSetting(c, "output", cfg.Output),
+		Columns:           columnsSetting(c, q.Columns, cfg.Columns),
+		IdentityFormat:    stringSetting(c, "identity-format", cfg.IdentityFormat),
+		PivotOn:           stringSetting(c, "pivot-on", q.PivotOn),
+		PivotWindow:       c.Duration("pivot-window"),
+		EventDataStore:    stringSetting(c, "event-data-store", cfg.EventDataStore),
+		PrintSQL:          c.Bool("print-sql"),
+		Redactor:          newRedactor(c, cfg),
+		// Where expressions and source IPs of the query and the command line are combined
+		Where:     append(append([]string{}, q.Where...), c.StringSlice("where")...),
+		SourceIPs: append(append([]string{}, q.SourceIP...), c.StringSlice("source-ip")...),
+		GroupBy:   groupBySetting(c, q),
+	}
+}
+
+// effectiveConfig returns the configuration with the flags given on the command line applied
+func effectiveConfig(c *cli.Command, cfg *config.Config) config.Config {
+	i := buildCloudTrailCliInput(c, cfg, nil)
+
+	effective := *cfg
+	effective.Profile = i.Profile
+	effective.Region = i.Region
+	effective.Columns = i.Columns
+	effective.Output = i.Output
+	effective.IdentityFormat = i.IdentityFormat
+	effective.MaxResults = i.MaxResults
+	effective.EventDataStore = i.EventDataStore
+	effective.GeoIPDatabase = stringSetting(c, "geoip-db", cfg.GeoIPDatabase)
+	effective.ASNDatabase = stringSetting(c, "asn-db", cfg.ASNDatabase)
+	effective.IPRanges = stringSetting(c, "ip-ranges", cfg.IPRanges)
+	effective.IPLabels = ipLabelsSetting(c, cfg)
+	effective.AccountsFile = stringSetting(c, "accounts-file", cfg.AccountsFile)
+	effective.Truncate = config.Truncate{}
+	if i.TruncateUserName {
+		effective.Truncate.UserName = utils.TruncateLength(i.UserNameLength)
+	}
+	if i.TruncateUserAgent {
+		effective.Truncate.UserAgent = utils.TruncateLength(i.UserAgentLength)
+	}
+	return effective
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/guessi/cloudtrail-cli/pkg/accounts"
+	"github.com/guessi/cloudtrail-cli/pkg/config"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/urfave/cli/v3"
+)
+
+// freshFlags copies the root flags so every run starts from their defaults
+func freshFlags() []cli.Flag {
+	flags := make([]cli.Flag, 0, len(Flags))
+	for _, flag := range Flags {
+		value := reflect.ValueOf(flag).Elem()
+		copied := reflect.New(value.Type())
+		copied.Elem().Set(value)
+		flags = append(flags, copied.Interface().(cli.Flag))
+	}
+	return flags
+}
+
+// parseInput runs the root flags over args and merges them with the configuration
+func parseInput(t *testing.T, args []string, cfg *config.Config, q *config.Query) types.CloudTrailCliInput {
+	t.Helper()
+
+	var input types.CloudTrailCliInput
+	app := &cli.Command{
+		Name:  "cloudtrail-cli",
+		Flags: freshFlags(),
+		Action: func(ctx context.Context, c *cli.Command) error {
+			input = buildCloudTrailCliInput(c, cfg, q)
+			return nil
+		},
+	}
+	if err := app.Run(context.Background(), append([]string{"cloudtrail-cli"}, args...)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return input
+}
+
+func TestBuildCloudTrailCliInput(t *testing.T) {
+	readOnly := false
+	cfg := &config.Config{
+		Profile:    "audit",
+		Region:     "eu-west-1",
+		Columns:    []string{"EventTime", "EventName"},
+		Output:     "json",
+		MaxResults: 100,
+		Truncate:   config.Truncate{UserAgent: 40},
+	}
+	query := &config.Query{
+		EventSource: "iam.amazonaws.com",
+		ReadOnly:    &readOnly,
+		ErrorOnly:   true,
+		Since:       time.Hour,
+		MaxResults:  500,
+		Where:       []string{"ErrorCode=AccessDenied"},
+	}
+
+	t.Run("Configuration defaults", func(t *testing.T) {
+		i := parseInput(t, nil, cfg, nil)
+		if i.Profile != "audit" || i.Region != "eu-west-1" || i.Output != "json" || i.MaxResults != 100 {
+			t.Errorf("Expected configuration defaults, got %+v", i)
+		}
+		if !reflect.DeepEqual(i.Columns, cfg.Columns) {
+			t.Errorf("Expected configured columns, got %v", i.Columns)
+		}
+		if i.TruncateUserName || !i.TruncateUserAgent || i.UserAgentLength != 40 {
+			t.Errorf("Expected only user agents truncated to 40, got %+v", i)
+		}
+		if i.IdentityFormat != "session" {
+			t.Errorf("Expected flag default identity format, got %q", i.IdentityFormat)
+		}
+	})
+
+	t.Run("Flags override configuration", func(t *testing.T) {
+		i := parseInput(t, []string{"--region", "us-east-1", "-o", "csv", "--columns", "EventId", "--truncate-user-agent=false", "-n", "5"}, cfg, nil)
+		if i.Profile != "audit" || i.Region != "us-east-1" || i.Output != "csv" || i.MaxResults != 5 {
+			t.Errorf("Expected flags to override configuration, got %+v", i)
+		}
+		if !reflect.DeepEqual(i.Columns, []string{"EventId"}) {
+			t.Errorf("Expected flag columns, got %v", i.Columns)
+		}
+		if i.TruncateUserAgent {
+			t.Error("Expected the flag to disable truncation")
+		}
+	})
+
+	t.Run("Named query", func(t *testing.T) {
+		before := time.Now()
+		i := parseInput(t, []string{"--where", "Username!=deploy-bot"}, cfg, query)
+		if i.EventSource != "iam.amazonaws.com" || !i.IsReadOnlyFlagSet || i.ReadOnly || !i.ErrorOnly || i.MaxResults != 500 {
+			t.Errorf("Expected query filters, got %+v", i)
+		}
+		if i.StartTime.Before(before.Add(-time.Hour-time.Second)) || i.StartTime.After(time.Now().Add(-time.Hour)) {
+			t.Errorf("Expected a window starting one hour ago, got %v", i.StartTime)
+		}
+		if !reflect.DeepEqual(i.Where, []string{"ErrorCode=AccessDenied", "Username!=deploy-bot"}) {
+			t.Errorf("Expected combined where expressions, got %v", i.Where)
+		}
+	})
+
+	t.Run("Flags override named query", func(t *testing.T) {
+		i := parseInput(t, []string{"--event-source", "sts.amazonaws.com", "--start-time", "2025-05-12T00:00:00Z"}, cfg, query)
+		if i.EventSource != "sts.amazonaws.com" {
+			t.Errorf("Expected flag event source, got %q", i.EventSource)
+		}
+		if !i.StartTime.Equal(time.Date(2025, 5, 12, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected flag start time, got %v", i.StartTime)
+		}
+	})
+}
+
+func TestNewRedactor(t *testing.T) {
+	disabled := false
+	testCases := []struct {
+		name     string
+		args     []string
+		cfg      *config.Config
+		expected string
+	}{
+		{
+			name:     "Disabled by default",
+			args:     nil,
+			cfg:      &config.Config{},
+			expected: "123456789012 203.0.113.10",
+		},
+		{
+			name:     "Enabled with the default rules",
+			args:     []string{"--redact"},
+			cfg:      &config.Config{},
+			expected: "account-1 ip-1",
+		},
+		{
+			name:     "Enabled by the configuration file",
+			args:     nil,
+			cfg:      &config.Config{Redact: config.Redact{Enabled: true, IPAddresses: &disabled}},
+			expected: "account-1 203.0.113.10",
+		},
+		{
+			name:     "Flag overrides the configuration file",
+			args:     []string{"--redact=false"},
+			cfg:      &config.Config{Redact: config.Redact{Enabled: true}},
+			expected: "123456789012 203.0.113.10",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			i := parseInput(t, tc.args, tc.cfg, nil)
+			if got := i.Redactor.Text("123456789012 203.0.113.10"); got != tc.expected {
+				t.Errorf("Text() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOpenAccounts(t *testing.T) {
+	export := filepath.Join("..", "pkg", "accounts", "testdata", "list-accounts.json")
+	testCases := []struct {
+		name        string
+		args        []string
+		cfg         *config.Config
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "No aliases",
+			cfg:      &config.Config{},
+			expected: "",
+		},
+		{
+			name:     "Exported accounts",
+			args:     []string{"--accounts-file", export},
+			cfg:      &config.Config{},
+			expected: "production",
+		},
+		{
+			name:     "Configured aliases override the export",
+			cfg:      &config.Config{AccountsFile: export, Accounts: map[string]string{"444455556666": "prod"}},
+			expected: "prod",
+		},
+		{
+			name:        "Missing export",
+			args:        []string{"--accounts-file", filepath.Join("..", "missing.json")},
+			cfg:         &config.Config{},
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var aliases *accounts.Aliases
+			var err error
+			app := &cli.Command{
+				Name:  "cloudtrail-cli",
+				Flags: freshFlags(),
+				Action: func(ctx context.Context, c *cli.Command) error {
+					aliases, err = openAccounts(c, tc.cfg)
+					return nil
+				},
+			}
+			if runErr := app.Run(context.Background(), append([]string{"cloudtrail-cli"}, tc.args...)); runErr != nil {
+				t.Fatalf("Unexpected error: %v", runErr)
+			}
+			if (err != nil) != tc.expectError {
+				t.Fatalf("openAccounts() error = %v, expectError %v", err, tc.expectError)
+			}
+			if got := aliases.Name("444455556666"); got != tc.expected {
+				t.Errorf("Name() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
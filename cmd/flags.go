@@ -3,10 +3,16 @@ package cmd
 import (
 	"time"
 
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
 	"github.com/urfave/cli/v3"
 )
 
 var Flags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "config",
+		Usage:    "Configuration file (default: ~/.config/cloudtrail-cli/config.yaml)",
+		Required: false,
+	},
 	&cli.StringFlag{
 		Name:     "profile",
 		Aliases:  []string{"p"},
@@ -17,6 +23,11 @@ var Flags = []cli.Flag{
 		Aliases:  []string{"r"},
 		Required: false,
 	},
+	&cli.StringFlag{
+		Name:     "endpoint-url",
+		Usage:    "Send CloudTrail API requests to this endpoint instead of the AWS one",
+		Required: false,
+	},
 	&cli.TimestampFlag{
 		Name:    "start-time",
 		Aliases: []string{"s"},
@@ -88,6 +99,11 @@ var Flags = []cli.Flag{
 		Usage:    "Filter events with errors",
 		Required: false,
 	},
+	&cli.BoolFlag{
+		Name:     "insights",
+		Usage:    "Lookup Insights events instead of management events",
+		Required: false,
+	},
 	&cli.BoolFlag{
 		Name:     "truncate-user-name",
 		Usage:    "Truncate user name string",
@@ -100,4 +116,161 @@ var Flags = []cli.Flag{
 		Value:    false,
 		Required: false,
 	},
+	&cli.StringFlag{
+		Name:     "output",
+		Aliases:  []string{"o"},
+		Usage:    "Output format: table, csv or json",
+		Value:    "table",
+		Required: false,
+	},
+	&cli.BoolFlag{
+		Name:     "redact",
+		Usage:    "Replace account IDs, access key IDs, IP addresses and secrets with pseudonyms",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "columns",
+		Usage:    "Comma-separated list of columns to display, run 'columns' to list them",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "identity-format",
+		Usage:    "Display assumed role identities as role, session, role/session or arn",
+		Value:    "session",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "pivot-on",
+		Usage:    "Also fetch events related to the results by user, access-key, ip or request-id",
+		Required: false,
+	},
+	&cli.DurationFlag{
+		Name:     "pivot-window",
+		Usage:    "Time around the results to search related events in",
+		Value:    constants.DefaultPivotWindow,
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "event-data-store",
+		Usage:    "Search a CloudTrail Lake event data store (ID or ARN) instead of the event history",
+		Required: false,
+	},
+	&cli.BoolFlag{
+		Name:     "print-sql",
+		Usage:    "Print the Lake query generated for --event-data-store instead of running it",
+		Required: false,
+	},
+	&cli.BoolFlag{
+		Name:     "tui",
+		Usage:    "Browse events in an interactive terminal interface",
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "where",
+		Usage:    "Filter events with Field=value or Field!=value, '*' as wildcard (repeatable)",
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "source-ip",
+		Usage:    "Filter events with source IP addresses or CIDR blocks, comma-separated, '!' to negate (repeatable)",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "group-by",
+		Usage:    "Comma-separated list of fields to count events by instead of listing them",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "geoip-db",
+		Usage:    "MaxMind City or Country database (.mmdb) providing the Country and City fields",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "asn-db",
+		Usage:    "MaxMind ASN database (.mmdb) providing the ASN and ASOrganization fields",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "ip-ranges",
+		Usage:    "Local copy of the AWS ip-ranges.json providing the SourceIPService and SourceIPRegion fields",
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:     "ip-labels",
+		Usage:    "File of 'CIDR label' lines providing the SourceIPLabel field (repeatable)",
+		Required: false,
+	},
+	&cli.StringFlag{
+		Name:     "accounts-file",
+		Usage:    "Output of 'aws organizations list-accounts' naming the accounts shown",
+		Required: false,
+	},
+	&cli.BoolFlag{
+		Name:     "exclude-known-ips",
+		Usage:    "Hide events from source IP addresses matched by --ip-labels",
+		Required: false,
+	},
+}
+
+var TraceRoleFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "format",
+		Aliases:  []string{"f"},
+		Usage:    "Output format: tree, dot or mermaid",
+		Value:    "tree",
+		Required: false,
+	},
+}
+
+var DetectFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:     "rule",
+		Usage:    "Only evaluate the given rule(s), run 'detect rules' to list them",
+		Required: false,
+	},
+}
+
+var SigmaFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:     "rules",
+		Usage:    "Sigma rule file(s) or directories",
+		Required: true,
+	},
+	&cli.StringSliceFlag{
+		Name:     "events-file",
+		Usage:    "Evaluate downloaded CloudTrail log file(s) or directories instead of calling LookupEvents",
+		Required: false,
+	},
+}
+
+var PolicyGenFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:     "include-resources",
+		Usage:    "Scope actions to the resource ARNs found in their requestParameters",
+		Value:    false,
+		Required: false,
+	},
+}
+
+var LakeQueryFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "file",
+		Aliases:  []string{"f"},
+		Usage:    "Read the SQL statement from a file",
+		Required: false,
+	},
+	&cli.DurationFlag{
+		Name:     "poll-interval",
+		Usage:    "Interval between query status checks",
+		Value:    constants.DefaultPollInterval,
+		Required: false,
+	},
+}
+
+var ValidateFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:     "public-key",
+		Usage:    "Public key file(s): the output of aws cloudtrail list-public-keys, or PEM/DER encoded keys",
+		Required: true,
+	},
 }
@@ -1,36 +1,234 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/config"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
 	"github.com/guessi/cloudtrail-cli/pkg/types"
 	"github.com/guessi/cloudtrail-cli/pkg/utils"
 	"github.com/urfave/cli/v3"
 )
 
+// newCloudTrailCliInput collects the shared lookup flags into handler input,
+// falling back to the configuration file for the flags not set
+func newCloudTrailCliInput(c *cli.Command) (types.CloudTrailCliInput, error) {
+	cfg, _, err := loadConfig(c)
+	if err != nil {
+		return types.CloudTrailCliInput{}, err
+	}
+	return newEnrichedInput(c, cfg, nil)
+}
+
+// newEnrichedInput builds the handler input and attaches the GeoIP databases,
+// IP ranges and account aliases. Callers close the GeoIP databases once done.
+func newEnrichedInput(c *cli.Command, cfg *config.Config, q *config.Query) (types.CloudTrailCliInput, error) {
+	i := buildCloudTrailCliInput(c, cfg, q)
+	resolver, err := openGeoIP(c, cfg)
+	if err != nil {
+		return types.CloudTrailCliInput{}, err
+	}
+	i.GeoIP = resolver
+	tagger, err := openIPTags(c, cfg)
+	if err != nil {
+		resolver.Close()
+		return types.CloudTrailCliInput{}, err
+	}
+	i.IPTags = tagger
+	aliases, err := openAccounts(c, cfg)
+	if err != nil {
+		resolver.Close()
+		return types.CloudTrailCliInput{}, err
+	}
+	i.Accounts = aliases
+	return i, nil
+}
+
 func Wrapper(c *cli.Command) error {
-	var isReadOnlyFlagSet bool
-	if c.IsSet("read-only") {
-		isReadOnlyFlagSet = true
-	}
-
-	cloudTrailCliInput := types.CloudTrailCliInput{
-		Profile:           c.String("profile"),
-		Region:            c.String("region"),
-		StartTime:         c.Timestamp("start-time"),
-		EndTime:           c.Timestamp("end-time"),
-		EventId:           c.String("event-id"),
-		EventName:         c.String("event-name"),
-		UserName:          c.String("user-name"),
-		ResourceName:      c.String("resource-name"),
-		ResourceType:      c.String("resource-type"),
-		EventSource:       c.String("event-source"),
-		AccessKeyId:       c.String("access-key-id"),
-		IsReadOnlyFlagSet: isReadOnlyFlagSet,
-		ReadOnly:          c.Bool("read-only"),
-		MaxResults:        c.Int("max-results"),
-		ErrorOnly:         c.Bool("error-only"),
-		TruncateUserName:  c.Bool("truncate-user-name"),
-		TruncateUserAgent: c.Bool("truncate-user-agent"),
-	}
-
-	return utils.EventsHandler(cloudTrailCliInput)
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	return listEvents(c, i)
+}
+
+// listEvents shows the events in the table or, with --tui, the interactive browser
+func listEvents(c *cli.Command, i types.CloudTrailCliInput) error {
+	if c.Bool("tui") {
+		return utils.TUIHandler(i)
+	}
+	return utils.EventsHandler(i)
+}
+
+func SessionWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	return utils.SessionHandler(i)
+}
+
+func TraceRoleWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.GraphFormat = c.String("format")
+	return utils.TraceRoleHandler(i)
+}
+
+func DetectWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.Rules = c.StringSlice("rule")
+	return utils.DetectHandler(i)
+}
+
+func ShowWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	if eventId := c.Args().First(); eventId != "" {
+		i.EventId = eventId
+	}
+	return utils.ShowHandler(i)
+}
+
+func ListDetectionRules() {
+	utils.RenderDetectionRules()
+}
+
+func ListColumns() {
+	utils.RenderColumns()
+}
+
+func SigmaWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.SigmaRules = c.StringSlice("rules")
+	i.EventFiles = c.StringSlice("events-file")
+	return utils.SigmaHandler(i)
+}
+
+func PolicyGenWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.IncludeResources = c.Bool("include-resources")
+	// A policy built from part of the window misses actions, so the whole
+	// window is read unless --max-results is given
+	if !c.IsSet("max-results") {
+		i.MaxResults = constants.MaxCloudTrailResults
+	}
+	return utils.PolicyGenHandler(i)
+}
+
+func RunWrapper(c *cli.Command) error {
+	cfg, _, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().First()
+	if name == "" {
+		utils.RenderQueries(cfg)
+		return nil
+	}
+
+	query, err := cfg.Query(name)
+	if err != nil {
+		return err
+	}
+	i, err := newEnrichedInput(c, cfg, &query)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	return listEvents(c, i)
+}
+
+func ConfigShowWrapper(c *cli.Command) error {
+	cfg, path, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	return utils.RenderConfig(path, effectiveConfig(c, cfg))
+}
+
+func LakeQueryWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.PollInterval = c.Duration("poll-interval")
+	i.LakeQuery = strings.Join(c.Args().Slice(), " ")
+
+	if path := c.String("file"); path != "" {
+		if i.LakeQuery != "" {
+			return fmt.Errorf("pass the SQL statement either as argument or with --file, not both")
+		}
+		statement, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read SQL statement from %s: %w", path, err)
+		}
+		i.LakeQuery = strings.TrimSpace(string(statement))
+	}
+	return utils.LakeQueryHandler(i)
+}
+
+func TrailsListWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	return utils.TrailsListHandler(i)
+}
+
+func TrailsStatusWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	return utils.TrailsStatusHandler(i, c.Args().First())
+}
+
+func ValidateWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	i.LogDirectory = c.Args().First()
+	i.PublicKeyFiles = c.StringSlice("public-key")
+	return utils.ValidateHandler(i)
+}
+
+func LoginsWrapper(c *cli.Command) error {
+	i, err := newCloudTrailCliInput(c)
+	if err != nil {
+		return err
+	}
+	defer i.GeoIP.Close()
+	if !c.IsSet("columns") {
+		i.Columns = utils.LoginColumns
+	}
+	return utils.LoginsHandler(i)
 }
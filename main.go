@@ -30,6 +30,144 @@ func main() {
 			return cmd.Wrapper(c)
 		},
 		Commands: []*cli.Command{
+			{
+				Name:  "session",
+				Usage: "Reconstruct activity and issuing chain of an access key (requires --access-key-id)",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.SessionWrapper(c)
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Render a single event in full",
+				ArgsUsage: "<event-id>",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.ShowWrapper(c)
+				},
+			},
+			{
+				Name:  "trace-role",
+				Usage: "Reconstruct who assumed which role from where, including chained assumptions",
+				Flags: cmd.TraceRoleFlags,
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.TraceRoleWrapper(c)
+				},
+			},
+			{
+				Name:  "detect",
+				Usage: "Evaluate built-in security detection rules over the events",
+				Flags: cmd.DetectFlags,
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.DetectWrapper(c)
+				},
+				Commands: []*cli.Command{
+					{
+						Name:  "rules",
+						Usage: "List built-in detection rules",
+						Action: func(context.Context, *cli.Command) error {
+							cmd.ListDetectionRules()
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "sigma",
+				Usage: "Evaluate Sigma rules with the aws/cloudtrail logsource",
+				Flags: cmd.SigmaFlags,
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.SigmaWrapper(c)
+				},
+			},
+			{
+				Name:  "policy-gen",
+				Usage: "Generate a least-privilege IAM policy from the activity of --user-name or --access-key-id",
+				Flags: cmd.PolicyGenFlags,
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.PolicyGenWrapper(c)
+				},
+			},
+			{
+				Name:  "lake",
+				Usage: "Query CloudTrail Lake event data stores",
+				Commands: []*cli.Command{
+					{
+						Name:      "query",
+						Usage:     "Run a SQL query and print its results",
+						ArgsUsage: "<sql>",
+						Flags:     cmd.LakeQueryFlags,
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.LakeQueryWrapper(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "logins",
+				Usage: "Report console logins, failures per user and successful logins after repeated failures",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.LoginsWrapper(c)
+				},
+			},
+			{
+				Name:      "validate",
+				Usage:     "Validate downloaded log files against their digest files and signatures",
+				ArgsUsage: "<directory>",
+				Flags:     cmd.ValidateFlags,
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.ValidateWrapper(c)
+				},
+			},
+			{
+				Name:  "trails",
+				Usage: "Inspect the trails and event data stores recording events",
+				Commands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List trails and event data stores with the regions they cover",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.TrailsListWrapper(c)
+						},
+					},
+					{
+						Name:      "status",
+						Usage:     "Show logging state, delivery errors and selected events of trails",
+						ArgsUsage: "[trail]",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.TrailsStatusWrapper(c)
+						},
+					},
+				},
+			},
+			{
+				Name:      "run",
+				Usage:     "Run a named query from the configuration file, or list them",
+				ArgsUsage: "[query]",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return cmd.RunWrapper(c)
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Inspect the configuration file",
+				Commands: []*cli.Command{
+					{
+						Name:  "show",
+						Usage: "Print the effective settings",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.ConfigShowWrapper(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "columns",
+				Usage: "List the fields available to --columns and --where",
+				Action: func(context.Context, *cli.Command) error {
+					cmd.ListColumns()
+					return nil
+				},
+			},
 			{
 				Name:    "version",
 				Aliases: []string{"v"},
@@ -0,0 +1,150 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	accountIdPattern = regexp.MustCompile(`\b\d{12}\b`)
+
+	// uuidPrefix matches the start of a UUID whose last group would look like an account ID
+	uuidPrefix = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-$`)
+
+	validAccountId = regexp.MustCompile(`^\d{12}$`)
+)
+
+// listAccountsOutput is the output of "aws organizations list-accounts"
+type listAccountsOutput struct {
+	Accounts []struct {
+		Id   string `json:"Id"`
+		Name string `json:"Name"`
+	} `json:"Accounts"`
+}
+
+// Load reads account names from a JSON export of the Organizations
+// ListAccounts API, e.g. "aws organizations list-accounts > accounts.json"
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read accounts file: %w", err)
+	}
+	var output listAccountsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid accounts file %s: %w", path, err)
+	}
+
+	names := make(map[string]string, len(output.Accounts))
+	for _, account := range output.Accounts {
+		names[account.Id] = account.Name
+	}
+	return names, nil
+}
+
+// Aliases maps account IDs to friendly names. A nil Aliases knows no account.
+type Aliases struct {
+	names map[string]string
+}
+
+// New merges alias maps, later ones overriding earlier ones. Without any alias
+// the returned Aliases is nil.
+func New(maps ...map[string]string) (*Aliases, error) {
+	names := make(map[string]string)
+	for _, m := range maps {
+		for id, name := range m {
+			id = strings.TrimSpace(id)
+			if !validAccountId.MatchString(id) {
+				return nil, fmt.Errorf("invalid account ID %q: expected 12 digits", id)
+			}
+			if name = strings.TrimSpace(name); name != "" {
+				names[id] = name
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return &Aliases{names: names}, nil
+}
+
+// Name returns the alias of an account ID, or an empty string
+func (a *Aliases) Name(id string) string {
+	if a == nil {
+		return ""
+	}
+	return a.names[id]
+}
+
+// Find returns the account IDs mentioned in a value, in order of appearance
+func Find(s string) []string {
+	var ids []string
+	for _, m := range accountIdPattern.FindAllStringIndex(s, -1) {
+		if uuidPrefix.MatchString(s[:m[0]]) {
+			continue
+		}
+		if id := s[m[0]:m[1]]; !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Names returns the aliases of the account IDs mentioned in a value
+func (a *Aliases) Names(s string) []string {
+	if a == nil {
+		return nil
+	}
+	var names []string
+	for _, id := range Find(s) {
+		if name := a.names[id]; name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Annotate appends the aliases of the account IDs found on each line of a
+// value, leaving the value itself intact: "arn:aws:iam::123456789012:root"
+// becomes "arn:aws:iam::123456789012:root (production)".
+func (a *Aliases) Annotate(s string) string {
+	if a == nil || s == "" {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for n, line := range lines {
+		if names := a.Names(line); len(names) > 0 {
+			lines[n] = line + " (" + strings.Join(names, ", ") + ")"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Mentioned returns the account IDs mentioned in a value that have an alias,
+// sorted
+func (a *Aliases) Mentioned(s string) []string {
+	if a == nil {
+		return nil
+	}
+	var ids []string
+	for _, id := range Find(s) {
+		if a.names[id] != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// contains reports whether a slice holds a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
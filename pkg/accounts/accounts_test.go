@@ -0,0 +1,137 @@
+package accounts
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	names, err := Load(filepath.Join("testdata", "list-accounts.json"))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	expected := map[string]string{"111122223333": "management", "444455556666": "production"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Load() = %v, want %v", names, expected)
+	}
+
+	if _, err := Load(filepath.Join("testdata", "missing.json")); err == nil {
+		t.Error("Load() of a missing file should fail")
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name        string
+		maps        []map[string]string
+		expected    string
+		expectError bool
+		expectNil   bool
+	}{
+		{
+			name:     "Later maps override earlier ones",
+			maps:     []map[string]string{{"444455556666": "production"}, {"444455556666": "prod"}},
+			expected: "prod",
+		},
+		{
+			name:      "No alias",
+			maps:      []map[string]string{nil, {"444455556666": " "}},
+			expectNil: true,
+		},
+		{
+			name:        "Invalid account ID",
+			maps:        []map[string]string{{"44445555666": "production"}},
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(tc.maps...)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("New() error = %v, expectError %v", err, tc.expectError)
+			}
+			if tc.expectError {
+				return
+			}
+			if (a == nil) != tc.expectNil {
+				t.Fatalf("New() = %v, expectNil %v", a, tc.expectNil)
+			}
+			if got := a.Name("444455556666"); got != tc.expected {
+				t.Errorf("Name() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	a, err := New(map[string]string{"111122223333": "management", "444455556666": "production"})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		aliases  *Aliases
+		input    string
+		expected string
+	}{
+		{
+			name:     "Account ID",
+			aliases:  a,
+			input:    "444455556666",
+			expected: "444455556666 (production)",
+		},
+		{
+			name:     "ARN",
+			aliases:  a,
+			input:    "arn:aws:iam::444455556666:role/Admin",
+			expected: "arn:aws:iam::444455556666:role/Admin (production)",
+		},
+		{
+			name:     "Several accounts on one line",
+			aliases:  a,
+			input:    "arn:aws:sts::444455556666:assumed-role/Admin/alice via 111122223333 and 444455556666",
+			expected: "arn:aws:sts::444455556666:assumed-role/Admin/alice via 111122223333 and 444455556666 (production, management)",
+		},
+		{
+			name:     "Each line of a multi-valued cell",
+			aliases:  a,
+			input:    "AWS::IAM::Role arn:aws:iam::111122223333:role/Audit\nAWS::S3::Bucket reports",
+			expected: "AWS::IAM::Role arn:aws:iam::111122223333:role/Audit (management)\nAWS::S3::Bucket reports",
+		},
+		{
+			name:     "Unknown account",
+			aliases:  a,
+			input:    "arn:aws:iam::999988887777:root",
+			expected: "arn:aws:iam::999988887777:root",
+		},
+		{
+			name:     "Event IDs are not account IDs",
+			aliases:  a,
+			input:    "6f1a2b3c-4d5e-4f70-8192-444455556666",
+			expected: "6f1a2b3c-4d5e-4f70-8192-444455556666",
+		},
+		{
+			name:     "Nil aliases",
+			aliases:  nil,
+			input:    "444455556666",
+			expected: "444455556666",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.aliases.Annotate(tc.input); got != tc.expected {
+				t.Errorf("Annotate(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+
+	if got := a.Mentioned(`{"recipientAccountId": "444455556666", "accountId": "111122223333", "other": "999988887777"}`); !reflect.DeepEqual(got, []string{"111122223333", "444455556666"}) {
+		t.Errorf("Mentioned() = %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Truncate holds the lengths user names and user agents are truncated to.
+// A positive length enables truncation.
+type Truncate struct {
+	UserName  int `yaml:"user-name,omitempty"`
+	UserAgent int `yaml:"user-agent,omitempty"`
+}
+
+// Redact selects what --redact masks. Unset kinds of values are redacted and
+// requestParameters paths are redacted in addition to the default ones.
+type Redact struct {
+	Enabled           bool     `yaml:"enabled,omitempty"`
+	AccountIds        *bool    `yaml:"account-ids,omitempty"`
+	AccessKeyIds      *bool    `yaml:"access-key-ids,omitempty"`
+	IPAddresses       *bool    `yaml:"ip-addresses,omitempty"`
+	RequestParameters []string `yaml:"request-parameters,omitempty"`
+}
+
+// Query is a named set of filters run with "cloudtrail-cli run <name>".
+// Keys mirror the command line flags.
+type Query struct {
+	Description     string        `yaml:"description,omitempty"`
+	EventId         string        `yaml:"event-id,omitempty"`
+	EventName       string        `yaml:"event-name,omitempty"`
+	UserName        string        `yaml:"user-name,omitempty"`
+	ResourceName    string        `yaml:"resource-name,omitempty"`
+	ResourceType    string        `yaml:"resource-type,omitempty"`
+	EventSource     string        `yaml:"event-source,omitempty"`
+	AccessKeyId     string        `yaml:"access-key-id,omitempty"`
+	ReadOnly        *bool         `yaml:"read-only,omitempty"`
+	ErrorOnly       bool          `yaml:"error-only,omitempty"`
+	Insights        bool          `yaml:"insights,omitempty"`
+	Since           time.Duration `yaml:"since,omitempty"`
+	StartTime       time.Time     `yaml:"start-time,omitempty"`
+	EndTime         time.Time     `yaml:"end-time,omitempty"`
+	MaxResults      int           `yaml:"max-results,omitempty"`
+	Where           []string      `yaml:"where,omitempty"`
+	SourceIP        []string      `yaml:"source-ip,omitempty"`
+	GroupBy         []string      `yaml:"group-by,omitempty"`
+	ExcludeKnownIPs bool          `yaml:"exclude-known-ips,omitempty"`
+	Columns         []string      `yaml:"columns,omitempty"`
+	PivotOn         string        `yaml:"pivot-on,omitempty"`
+}
+
+// Config holds the defaults applied when the matching flags are not set
+type Config struct {
+	Profile        string            `yaml:"profile,omitempty"`
+	Region         string            `yaml:"region,omitempty"`
+	Columns        []string          `yaml:"columns,omitempty"`
+	Output         string            `yaml:"output,omitempty"`
+	IdentityFormat string            `yaml:"identity-format,omitempty"`
+	MaxResults     int               `yaml:"max-results,omitempty"`
+	EventDataStore string            `yaml:"event-data-store,omitempty"`
+	GeoIPDatabase  string            `yaml:"geoip-db,omitempty"`
+	ASNDatabase    string            `yaml:"asn-db,omitempty"`
+	IPRanges       string            `yaml:"ip-ranges,omitempty"`
+	IPLabels       []string          `yaml:"ip-labels,omitempty"`
+	AccountsFile   string            `yaml:"accounts-file,omitempty"`
+	Accounts       map[string]string `yaml:"accounts,omitempty"`
+	Truncate       Truncate          `yaml:"truncate,omitempty"`
+	Redact         Redact            `yaml:"redact,omitempty"`
+	Queries        map[string]Query  `yaml:"queries,omitempty"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/cloudtrail-cli/config.yaml, falling back
+// to ~/.config/cloudtrail-cli/config.yaml
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to locate home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cloudtrail-cli", "config.yaml"), nil
+}
+
+// Load reads a configuration file. A missing file yields an empty configuration
+// unless it was explicitly requested.
+func Load(path string, required bool) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Query returns a named query
+func (c *Config) Query(name string) (Query, error) {
+	query, ok := c.Queries[name]
+	if !ok {
+		if len(c.Queries) == 0 {
+			return Query{}, fmt.Errorf("unknown query %q, no queries are configured", name)
+		}
+		return Query{}, fmt.Errorf("unknown query %q, available queries: %v", name, c.QueryNames())
+	}
+	return query, nil
+}
+
+// QueryNames returns the names of the configured queries in alphabetical order
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
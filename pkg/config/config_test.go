@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := `
+profile: audit
+region: eu-west-1
+columns: [EventTime, EventName]
+truncate:
+  user-agent: 40
+queries:
+  failed-iam-changes:
+    description: Failed IAM write calls
+    event-source: iam.amazonaws.com
+    read-only: false
+    error-only: true
+    since: 24h
+    where: ["ErrorCode=AccessDenied"]
+  console-logins:
+    event-name: ConsoleLogin
+    start-time: 2025-05-12T00:00:00Z
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Profile != "audit" || cfg.Region != "eu-west-1" || cfg.Truncate.UserAgent != 40 {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+	if names := cfg.QueryNames(); !reflect.DeepEqual(names, []string{"console-logins", "failed-iam-changes"}) {
+		t.Errorf("Unexpected query names: %v", names)
+	}
+
+	query, err := cfg.Query("failed-iam-changes")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query.ReadOnly == nil || *query.ReadOnly || !query.ErrorOnly || query.Since != 24*time.Hour {
+		t.Errorf("Unexpected query: %+v", query)
+	}
+
+	query, _ = cfg.Query("console-logins")
+	if !query.StartTime.Equal(time.Date(2025, 5, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected start time: %v", query.StartTime)
+	}
+
+	if _, err := cfg.Query("missing"); err == nil {
+		t.Error("Expected error for an unknown query")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	cfg, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, &Config{}) {
+		t.Errorf("Expected an empty configuration, got %+v", cfg)
+	}
+
+	if _, err := Load(path, true); err == nil {
+		t.Error("Expected error for a missing explicit configuration file")
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("queries: [not, a, map]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, false); err == nil {
+		t.Error("Expected error for an invalid configuration file")
+	}
+}
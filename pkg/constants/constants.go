@@ -19,8 +19,62 @@ const (
 
 	// AWS service validation
 	AWSServiceSuffix = ".amazonaws.com"
+
+	// Event history retention of LookupEvents
+	EventHistoryRetention = 90 * 24 * time.Hour
+
+	// Credential tracing limits
+	MaxCredentialLifetime  = 36 * time.Hour // longest lifetime of GetSessionToken/GetFederationToken credentials
+	MaxCredentialChainHops = 10
+	MaxIssuerSearchResults = 1000 // events scanned over the whole chain
+
+	// CloudTrail Lake
+	DefaultPollInterval = 2 * time.Second
+
+	// Pivot limits
+	MaxPivotValues     = 25
+	DefaultPivotWindow = time.Hour
 )
 
+// STS events whose responseElements carry newly issued temporary credentials
+var CredentialIssuingEvents = []string{
+	"AssumeRole",
+	"AssumeRoleWithSAML",
+	"AssumeRoleWithWebIdentity",
+	"GetSessionToken",
+	"GetFederationToken",
+}
+
+// STS events that assume a role, used to reconstruct role assumption chains
+var RoleAssumptionEvents = []string{
+	"AssumeRole",
+	"AssumeRoleWithSAML",
+	"AssumeRoleWithWebIdentity",
+}
+
+// Display modes of --identity-format for assumed role sessions
+var IdentityFormats = []string{
+	"role",
+	"session",
+	"role/session",
+	"arn",
+}
+
+// Values of --output
+var OutputFormats = []string{
+	"table",
+	"csv",
+	"json",
+}
+
+// Values of --pivot-on
+var PivotKeys = []string{
+	"user",
+	"access-key",
+	"ip",
+	"request-id",
+}
+
 var (
 	GitVersion string
 	GoVersion  string
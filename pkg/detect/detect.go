@@ -0,0 +1,435 @@
+package detect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+type Severity string
+
+const (
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+// Rank orders severities from least to most severe
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityCritical:
+		return 4
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Finding is a rule match citing the events that triggered it
+type Finding struct {
+	Rule      string
+	Severity  Severity
+	EventTime string
+	Principal string
+	SourceIP  string
+	Summary   string
+	EventIds  []string
+}
+
+// Rule evaluates the whole event stream and reports its findings
+type Rule interface {
+	Name() string
+	Severity() Severity
+	Description() string
+	Evaluate(events []*types.CloudTrailEvent) []Finding
+}
+
+// eventRule reports one finding for every single event matching its predicate
+type eventRule struct {
+	name        string
+	severity    Severity
+	description string
+	match       func(e *types.CloudTrailEvent) bool
+}
+
+func (r eventRule) Name() string        { return r.name }
+func (r eventRule) Severity() Severity  { return r.severity }
+func (r eventRule) Description() string { return r.description }
+
+func (r eventRule) Evaluate(events []*types.CloudTrailEvent) []Finding {
+	var findings []Finding
+	for _, e := range events {
+		if !r.match(e) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:      r.name,
+			Severity:  r.severity,
+			EventTime: e.EventTime,
+			Principal: principalOf(e.UserIdentity),
+			SourceIP:  e.SourceIPAddress,
+			Summary:   r.description + ": " + e.EventName,
+			EventIds:  []string{e.EventId},
+		})
+	}
+	return findings
+}
+
+// burstRule reports principals that hit the predicate repeatedly within a short window
+type burstRule struct {
+	name        string
+	severity    Severity
+	description string
+	threshold   int
+	window      time.Duration
+	match       func(e *types.CloudTrailEvent) bool
+}
+
+func (r burstRule) Name() string        { return r.name }
+func (r burstRule) Severity() Severity  { return r.severity }
+func (r burstRule) Description() string { return r.description }
+
+// timedEvent pairs an event with its parsed timestamp
+type timedEvent struct {
+	at    time.Time
+	event *types.CloudTrailEvent
+}
+
+func (r burstRule) Evaluate(events []*types.CloudTrailEvent) []Finding {
+	byPrincipal := make(map[string][]timedEvent)
+	for _, e := range events {
+		if !r.match(e) {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, e.EventTime)
+		if err != nil {
+			continue
+		}
+		principal := principalOf(e.UserIdentity)
+		byPrincipal[principal] = append(byPrincipal[principal], timedEvent{at: at, event: e})
+	}
+
+	principals := make([]string, 0, len(byPrincipal))
+	for principal := range byPrincipal {
+		principals = append(principals, principal)
+	}
+	sort.Strings(principals)
+
+	var findings []Finding
+	for _, principal := range principals {
+		matched := byPrincipal[principal]
+		sort.Slice(matched, func(a, b int) bool { return matched[a].at.Before(matched[b].at) })
+
+		// Slide over the sorted events and report each maximal burst once
+		for start := 0; start < len(matched); {
+			end := start
+			for end+1 < len(matched) && matched[end+1].at.Sub(matched[start].at) <= r.window {
+				end++
+			}
+			if end-start+1 < r.threshold {
+				start++
+				continue
+			}
+			burst := matched[start : end+1]
+			ids := make([]string, 0, len(burst))
+			for _, te := range burst {
+				ids = append(ids, te.event.EventId)
+			}
+			findings = append(findings, Finding{
+				Rule:      r.name,
+				Severity:  r.severity,
+				EventTime: burst[0].event.EventTime,
+				Principal: principal,
+				SourceIP:  burst[0].event.SourceIPAddress,
+				Summary:   r.description,
+				EventIds:  ids,
+			})
+			start = end + 1
+		}
+	}
+	return findings
+}
+
+// loginAfterFailuresRule reports successful console logins preceded by
+// repeated failed ones from the same source IP within a window
+type loginAfterFailuresRule struct {
+	name        string
+	severity    Severity
+	description string
+	threshold   int
+	window      time.Duration
+}
+
+func (r loginAfterFailuresRule) Name() string        { return r.name }
+func (r loginAfterFailuresRule) Severity() Severity  { return r.severity }
+func (r loginAfterFailuresRule) Description() string { return r.description }
+
+func (r loginAfterFailuresRule) Evaluate(events []*types.CloudTrailEvent) []Finding {
+	byIP := make(map[string][]timedEvent)
+	for _, e := range events {
+		if e.EventName != "ConsoleLogin" || ConsoleLoginResult(e) == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, e.EventTime)
+		if err != nil {
+			continue
+		}
+		byIP[e.SourceIPAddress] = append(byIP[e.SourceIPAddress], timedEvent{at: at, event: e})
+	}
+
+	ips := make([]string, 0, len(byIP))
+	for ip := range byIP {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var findings []Finding
+	for _, ip := range ips {
+		logins := byIP[ip]
+		sort.SliceStable(logins, func(a, b int) bool { return logins[a].at.Before(logins[b].at) })
+
+		var failures []timedEvent
+		for _, login := range logins {
+			if ConsoleLoginResult(login.event) != "Success" {
+				failures = append(failures, login)
+				continue
+			}
+
+			// Only the failures within the window before the success count
+			var recent []timedEvent
+			for _, failure := range failures {
+				if login.at.Sub(failure.at) <= r.window {
+					recent = append(recent, failure)
+				}
+			}
+			failures = nil
+			if len(recent) < r.threshold {
+				continue
+			}
+
+			ids := make([]string, 0, len(recent)+1)
+			var targets []string
+			for _, failure := range recent {
+				ids = append(ids, failure.event.EventId)
+				if target := principalOf(failure.event.UserIdentity); !containsString(targets, target) {
+					targets = append(targets, target)
+				}
+			}
+			ids = append(ids, login.event.EventId)
+			findings = append(findings, Finding{
+				Rule:      r.name,
+				Severity:  r.severity,
+				EventTime: login.event.EventTime,
+				Principal: principalOf(login.event.UserIdentity),
+				SourceIP:  ip,
+				Summary:   fmt.Sprintf("%s: %d failures (%s)", r.description, len(recent), strings.Join(targets, ", ")),
+				EventIds:  ids,
+			})
+		}
+	}
+	return findings
+}
+
+// ConsoleLoginResult returns "Success" or "Failure" for console sign-in events
+func ConsoleLoginResult(e *types.CloudTrailEvent) string {
+	return types.LookupString(e.ResponseElements, "ConsoleLogin")
+}
+
+// containsString reports whether a string slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// principalOf returns a stable label for the caller of an event
+func principalOf(u types.UserIdentity) string {
+	switch {
+	case u.Arn != "":
+		return u.Arn
+	case u.InvokedBy != "":
+		return u.InvokedBy
+	case u.PrincipalId != "":
+		return u.PrincipalId
+	case u.Type == "Root" && u.AccountId != "":
+		return "root@" + u.AccountId
+	default:
+		return u.Type
+	}
+}
+
+// succeeded reports whether the API call completed without error
+func succeeded(e *types.CloudTrailEvent) bool {
+	return e.ErrorCode == ""
+}
+
+// containsKeyValue recursively searches nested JSON for key with one of the values
+func containsKeyValue(v interface{}, key string, values ...string) bool {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			if s, ok := child.(string); ok && k == key {
+				for _, value := range values {
+					if s == value {
+						return true
+					}
+				}
+			}
+			if containsKeyValue(child, key, values...) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range node {
+			if containsKeyValue(child, key, values...) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAccessDenied reports authorization failures across the naming variants services use
+func isAccessDenied(e *types.CloudTrailEvent) bool {
+	code := e.ErrorCode
+	return strings.HasSuffix(code, "AccessDenied") ||
+		strings.HasSuffix(code, "AccessDeniedException") ||
+		strings.HasSuffix(code, "UnauthorizedOperation")
+}
+
+// BuiltinRules is the library of detections evaluated by the detect subcommand
+var BuiltinRules = []Rule{
+	eventRule{
+		name:        "root-account-usage",
+		severity:    SeverityHigh,
+		description: "Root account credentials used",
+		match: func(e *types.CloudTrailEvent) bool {
+			return e.UserIdentity.Type == "Root" && e.UserIdentity.InvokedBy == ""
+		},
+	},
+	eventRule{
+		name:        "console-login-without-mfa",
+		severity:    SeverityMedium,
+		description: "Successful console login without MFA",
+		match: func(e *types.CloudTrailEvent) bool {
+			if e.EventName != "ConsoleLogin" || ConsoleLoginResult(e) != "Success" {
+				return false
+			}
+			if e.AdditionalEventData != nil && e.AdditionalEventData.MFAUsed == "Yes" {
+				return false
+			}
+			return e.UserIdentity.SessionContext.Attributes.MfaAuthenticated != "true"
+		},
+	},
+	eventRule{
+		name:        "cloudtrail-logging-disabled",
+		severity:    SeverityCritical,
+		description: "CloudTrail logging stopped or trail deleted",
+		match: func(e *types.CloudTrailEvent) bool {
+			return e.EventSource == "cloudtrail.amazonaws.com" && succeeded(e) &&
+				(e.EventName == "StopLogging" || e.EventName == "DeleteTrail")
+		},
+	},
+	eventRule{
+		name:        "security-group-open-to-world",
+		severity:    SeverityHigh,
+		description: "Security group ingress opened to 0.0.0.0/0 or ::/0",
+		match: func(e *types.CloudTrailEvent) bool {
+			if e.EventSource != "ec2.amazonaws.com" || e.EventName != "AuthorizeSecurityGroupIngress" || !succeeded(e) {
+				return false
+			}
+			return containsKeyValue(e.RequestParameters, "cidrIp", "0.0.0.0/0") ||
+				containsKeyValue(e.RequestParameters, "cidrIpv6", "::/0")
+		},
+	},
+	eventRule{
+		name:        "admin-policy-attached",
+		severity:    SeverityHigh,
+		description: "AdministratorAccess policy attached",
+		match: func(e *types.CloudTrailEvent) bool {
+			if e.EventSource != "iam.amazonaws.com" || !succeeded(e) {
+				return false
+			}
+			switch e.EventName {
+			case "AttachUserPolicy", "AttachRolePolicy", "AttachGroupPolicy":
+				return strings.HasSuffix(types.LookupString(e.RequestParameters, "policyArn"), ":policy/AdministratorAccess")
+			}
+			return false
+		},
+	},
+	eventRule{
+		name:        "kms-key-deletion-scheduled",
+		severity:    SeverityHigh,
+		description: "KMS key scheduled for deletion",
+		match: func(e *types.CloudTrailEvent) bool {
+			return e.EventSource == "kms.amazonaws.com" && e.EventName == "ScheduleKeyDeletion" && succeeded(e)
+		},
+	},
+	loginAfterFailuresRule{
+		name:        "console-login-after-failures",
+		severity:    SeverityHigh,
+		description: "Successful console login after repeated failures from the same IP",
+		threshold:   3,
+		window:      time.Hour,
+	},
+	burstRule{
+		name:        "access-denied-burst",
+		severity:    SeverityMedium,
+		description: "Burst of AccessDenied errors from one principal",
+		threshold:   10,
+		window:      5 * time.Minute,
+		match:       isAccessDenied,
+	},
+}
+
+// SelectRules returns the built-in rules with the given names, or all of them when none are given
+func SelectRules(names []string) ([]Rule, []string) {
+	if len(names) == 0 {
+		return BuiltinRules, nil
+	}
+
+	byName := make(map[string]Rule, len(BuiltinRules))
+	for _, rule := range BuiltinRules {
+		byName[rule.Name()] = rule
+	}
+
+	var rules []Rule
+	var unknown []string
+	for _, name := range names {
+		rule, ok := byName[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, unknown
+}
+
+// Evaluate runs every rule over the events and returns findings, most severe first
+func Evaluate(rules []Rule, events []*types.CloudTrailEvent) []Finding {
+	var findings []Finding
+	for _, rule := range rules {
+		findings = append(findings, rule.Evaluate(events)...)
+	}
+
+	sort.SliceStable(findings, func(a, b int) bool {
+		if findings[a].Severity.Rank() != findings[b].Severity.Rank() {
+			return findings[a].Severity.Rank() > findings[b].Severity.Rank()
+		}
+		return findings[a].EventTime < findings[b].EventTime
+	})
+	return findings
+}
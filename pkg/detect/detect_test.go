@@ -0,0 +1,209 @@
+package detect
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func mustParse(t *testing.T, raw string) *types.CloudTrailEvent {
+	t.Helper()
+	var e types.CloudTrailEvent
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatalf("failed to parse test event: %v", err)
+	}
+	return &e
+}
+
+func TestBuiltinRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    string
+		expected string
+	}{
+		{
+			name:     "Root account usage",
+			event:    `{"eventID": "e1", "eventName": "ListBuckets", "userIdentity": {"type": "Root", "accountId": "123456789012"}}`,
+			expected: "root-account-usage",
+		},
+		{
+			name:     "Console login without MFA",
+			event:    `{"eventID": "e2", "eventName": "ConsoleLogin", "responseElements": {"ConsoleLogin": "Success"}, "userIdentity": {"type": "IAMUser", "sessionContext": {"attributes": {"mfaAuthenticated": "false"}}}}`,
+			expected: "console-login-without-mfa",
+		},
+		{
+			name:     "Console login with MFA",
+			event:    `{"eventID": "e3", "eventName": "ConsoleLogin", "responseElements": {"ConsoleLogin": "Success"}, "userIdentity": {"type": "IAMUser", "sessionContext": {"attributes": {"mfaAuthenticated": "true"}}}}`,
+			expected: "",
+		},
+		{
+			name:     "Console login with MFA in additional event data",
+			event:    `{"eventID": "e3", "eventName": "ConsoleLogin", "responseElements": {"ConsoleLogin": "Success"}, "additionalEventData": {"MFAUsed": "Yes"}}`,
+			expected: "",
+		},
+		{
+			name:     "StopLogging",
+			event:    `{"eventID": "e4", "eventSource": "cloudtrail.amazonaws.com", "eventName": "StopLogging"}`,
+			expected: "cloudtrail-logging-disabled",
+		},
+		{
+			name:     "Failed DeleteTrail is ignored",
+			event:    `{"eventID": "e5", "eventSource": "cloudtrail.amazonaws.com", "eventName": "DeleteTrail", "errorCode": "AccessDenied"}`,
+			expected: "",
+		},
+		{
+			name: "Security group opened to the world",
+			event: `{"eventID": "e6", "eventSource": "ec2.amazonaws.com", "eventName": "AuthorizeSecurityGroupIngress",
+				"requestParameters": {"ipPermissions": {"items": [{"ipRanges": {"items": [{"cidrIp": "0.0.0.0/0"}]}}]}}}`,
+			expected: "security-group-open-to-world",
+		},
+		{
+			name: "Security group opened to a private range",
+			event: `{"eventID": "e7", "eventSource": "ec2.amazonaws.com", "eventName": "AuthorizeSecurityGroupIngress",
+				"requestParameters": {"ipPermissions": {"items": [{"ipRanges": {"items": [{"cidrIp": "10.0.0.0/8"}]}}]}}}`,
+			expected: "",
+		},
+		{
+			name:     "AdministratorAccess attached",
+			event:    `{"eventID": "e8", "eventSource": "iam.amazonaws.com", "eventName": "AttachRolePolicy", "requestParameters": {"policyArn": "arn:aws:iam::aws:policy/AdministratorAccess"}}`,
+			expected: "admin-policy-attached",
+		},
+		{
+			name:     "KMS key deletion scheduled",
+			event:    `{"eventID": "e9", "eventSource": "kms.amazonaws.com", "eventName": "ScheduleKeyDeletion"}`,
+			expected: "kms-key-deletion-scheduled",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			findings := Evaluate(BuiltinRules, []*types.CloudTrailEvent{mustParse(t, tc.event)})
+			if tc.expected == "" {
+				if len(findings) != 0 {
+					t.Errorf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 || findings[0].Rule != tc.expected {
+				t.Fatalf("expected a single %s finding, got %+v", tc.expected, findings)
+			}
+			if len(findings[0].EventIds) != 1 {
+				t.Errorf("finding should cite the triggering event, got %v", findings[0].EventIds)
+			}
+		})
+	}
+}
+
+func TestAccessDeniedBurst(t *testing.T) {
+	var events []*types.CloudTrailEvent
+	for idx := 0; idx < 12; idx++ {
+		events = append(events, mustParse(t, fmt.Sprintf(
+			`{"eventID": "d%d", "eventTime": "2025-05-12T00:00:%02dZ", "errorCode": "AccessDenied", "userIdentity": {"arn": "arn:aws:iam::123456789012:user/bob"}}`,
+			idx, idx*5)))
+	}
+	// A lone failure an hour later must not extend the burst
+	events = append(events, mustParse(t,
+		`{"eventID": "late", "eventTime": "2025-05-12T01:00:00Z", "errorCode": "AccessDenied", "userIdentity": {"arn": "arn:aws:iam::123456789012:user/bob"}}`))
+
+	rules, _ := SelectRules([]string{"access-denied-burst"})
+	findings := Evaluate(rules, events)
+	if len(findings) != 1 {
+		t.Fatalf("expected one burst finding, got %d", len(findings))
+	}
+	if len(findings[0].EventIds) != 12 {
+		t.Errorf("burst cites %d events, want 12", len(findings[0].EventIds))
+	}
+}
+
+func TestConsoleLoginAfterFailures(t *testing.T) {
+	login := func(id, at, ip, user, result string) *types.CloudTrailEvent {
+		return mustParse(t, fmt.Sprintf(
+			`{"eventID": %q, "eventName": "ConsoleLogin", "eventTime": %q, "sourceIPAddress": %q,
+				"userIdentity": {"arn": "arn:aws:iam::123456789012:user/%s"}, "responseElements": {"ConsoleLogin": %q}}`,
+			id, at, ip, user, result))
+	}
+
+	testCases := []struct {
+		name        string
+		events      []*types.CloudTrailEvent
+		expectedIds [][]string
+	}{
+		{
+			name: "Failures then success from the same IP",
+			events: []*types.CloudTrailEvent{
+				login("f1", "2025-05-12T00:00:00Z", "203.0.113.10", "alice", "Failure"),
+				login("f2", "2025-05-12T00:01:00Z", "203.0.113.10", "bob", "Failure"),
+				login("f3", "2025-05-12T00:02:00Z", "203.0.113.10", "alice", "Failure"),
+				login("s1", "2025-05-12T00:03:00Z", "203.0.113.10", "alice", "Success"),
+			},
+			expectedIds: [][]string{{"f1", "f2", "f3", "s1"}},
+		},
+		{
+			name: "Failures from another IP",
+			events: []*types.CloudTrailEvent{
+				login("f1", "2025-05-12T00:00:00Z", "198.51.100.7", "alice", "Failure"),
+				login("f2", "2025-05-12T00:01:00Z", "198.51.100.7", "alice", "Failure"),
+				login("f3", "2025-05-12T00:02:00Z", "198.51.100.7", "alice", "Failure"),
+				login("s1", "2025-05-12T00:03:00Z", "203.0.113.10", "alice", "Success"),
+			},
+		},
+		{
+			name: "Failures outside of the window",
+			events: []*types.CloudTrailEvent{
+				login("f1", "2025-05-12T00:00:00Z", "203.0.113.10", "alice", "Failure"),
+				login("f2", "2025-05-12T00:01:00Z", "203.0.113.10", "alice", "Failure"),
+				login("f3", "2025-05-12T00:02:00Z", "203.0.113.10", "alice", "Failure"),
+				login("s1", "2025-05-12T02:00:00Z", "203.0.113.10", "alice", "Success"),
+			},
+		},
+		{
+			name: "Too few failures",
+			events: []*types.CloudTrailEvent{
+				login("f1", "2025-05-12T00:00:00Z", "203.0.113.10", "alice", "Failure"),
+				login("f2", "2025-05-12T00:01:00Z", "203.0.113.10", "alice", "Failure"),
+				login("s1", "2025-05-12T00:03:00Z", "203.0.113.10", "alice", "Success"),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			rules, _ := SelectRules([]string{"console-login-after-failures"})
+			var ids [][]string
+			for _, finding := range Evaluate(rules, tc.events) {
+				ids = append(ids, finding.EventIds)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.expectedIds) {
+				t.Errorf("expected findings citing %v, got %v", tc.expectedIds, ids)
+			}
+		})
+	}
+}
+
+func TestSelectRules(t *testing.T) {
+	rules, unknown := SelectRules(nil)
+	if len(rules) != len(BuiltinRules) || len(unknown) != 0 {
+		t.Errorf("SelectRules(nil) should return every built-in rule")
+	}
+
+	rules, unknown = SelectRules([]string{"root-account-usage", "no-such-rule"})
+	if len(rules) != 1 || len(unknown) != 1 || unknown[0] != "no-such-rule" {
+		t.Errorf("SelectRules() = %d rules, unknown %v", len(rules), unknown)
+	}
+}
+
+func TestEvaluateOrdersBySeverity(t *testing.T) {
+	events := []*types.CloudTrailEvent{
+		mustParse(t, `{"eventID": "a", "eventTime": "2025-05-12T00:00:00Z", "userIdentity": {"type": "Root"}}`),
+		mustParse(t, `{"eventID": "b", "eventTime": "2025-05-12T00:01:00Z", "eventSource": "cloudtrail.amazonaws.com", "eventName": "StopLogging"}`),
+	}
+
+	findings := Evaluate(BuiltinRules, events)
+	if len(findings) != 2 || findings[0].Severity != SeverityCritical {
+		t.Errorf("expected critical finding first, got %+v", findings)
+	}
+}
@@ -0,0 +1,151 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// Location is what the databases know about an IP address. Fields the
+// databases have no data for are left empty.
+type Location struct {
+	Country        string
+	City           string
+	ASN            string
+	ASOrganization string
+}
+
+// cityRecord is the subset of a GeoIP2/GeoLite2 City or Country record in use
+type cityRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord is a GeoIP2/GeoLite2 ASN record
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Resolver looks up IP addresses in local MaxMind databases. Results are
+// cached per address. A Resolver is safe for concurrent use; a nil Resolver
+// resolves nothing.
+type Resolver struct {
+	city  *maxminddb.Reader
+	asn   *maxminddb.Reader
+	mu    sync.Mutex
+	cache map[string]Location
+}
+
+// openDatabase opens an MMDB file and checks its type against the expected one
+func openDatabase(path string, asn bool) (*maxminddb.Reader, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open MaxMind database %s: %w", path, err)
+	}
+	if strings.Contains(reader.Metadata.DatabaseType, "ASN") != asn {
+		reader.Close()
+		kind := "a City or Country"
+		if asn {
+			kind = "an ASN"
+		}
+		return nil, fmt.Errorf("%s is a %s database, expected %s database", path, reader.Metadata.DatabaseType, kind)
+	}
+	return reader, nil
+}
+
+// Open loads the City (or Country) and ASN databases. Either path may be
+// empty; without any path the returned Resolver is nil.
+func Open(cityPath, asnPath string) (*Resolver, error) {
+	if cityPath == "" && asnPath == "" {
+		return nil, nil
+	}
+
+	r := &Resolver{cache: make(map[string]Location)}
+	if cityPath != "" {
+		reader, err := openDatabase(cityPath, false)
+		if err != nil {
+			return nil, err
+		}
+		r.city = reader
+	}
+	if asnPath != "" {
+		reader, err := openDatabase(asnPath, true)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.asn = reader
+	}
+	return r, nil
+}
+
+// Close releases the databases
+func (r *Resolver) Close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	if r.city != nil {
+		errs = append(errs, r.city.Close())
+	}
+	if r.asn != nil {
+		errs = append(errs, r.asn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup returns the location of an address. Values that are not IP addresses,
+// such as the service principals CloudTrail reports for calls made by AWS
+// services, resolve to an empty Location.
+func (r *Resolver) Lookup(address string) Location {
+	if r == nil {
+		return Location{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if location, ok := r.cache[address]; ok {
+		return location
+	}
+
+	var location Location
+	if ip, err := netip.ParseAddr(address); err == nil {
+		location = r.lookup(ip.Unmap())
+	}
+	r.cache[address] = location
+	return location
+}
+
+// lookup reads an address from the databases. Decoding errors leave the
+// affected fields empty rather than failing the whole listing.
+func (r *Resolver) lookup(ip netip.Addr) Location {
+	var location Location
+	if r.city != nil {
+		var record cityRecord
+		if err := r.city.Lookup(ip).Decode(&record); err == nil {
+			location.Country = record.Country.ISOCode
+			if location.Country == "" {
+				location.Country = record.Country.Names["en"]
+			}
+			location.City = record.City.Names["en"]
+		}
+	}
+	if r.asn != nil {
+		var record asnRecord
+		if err := r.asn.Lookup(ip).Decode(&record); err == nil && record.Number > 0 {
+			location.ASN = "AS" + strconv.FormatUint(uint64(record.Number), 10)
+			location.ASOrganization = record.Organization
+		}
+	}
+	return location
+}
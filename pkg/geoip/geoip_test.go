@@ -0,0 +1,120 @@
+package geoip
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The test databases are written by the program in testdata/generate, a module
+// of its own so the writer stays out of the dependencies of the CLI
+//go:generate go -C testdata/generate run . ..
+
+var (
+	cityDatabase = filepath.Join("testdata", "GeoLite2-City-Test.mmdb")
+	asnDatabase  = filepath.Join("testdata", "GeoLite2-ASN-Test.mmdb")
+)
+
+func TestLookup(t *testing.T) {
+	r, err := Open(cityDatabase, asnDatabase)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer r.Close()
+
+	testCases := []struct {
+		name     string
+		address  string
+		expected Location
+	}{
+		{
+			name:     "IPv4 address",
+			address:  "203.0.113.10",
+			expected: Location{Country: "JP", City: "Tokyo", ASN: "AS64496", ASOrganization: "Example Networks"},
+		},
+		{
+			name:     "Country without city nor ASN",
+			address:  "198.51.100.7",
+			expected: Location{Country: "DE"},
+		},
+		{
+			name:     "IPv6 address",
+			address:  "2001:db8::8a2e:370:7334",
+			expected: Location{Country: "US", City: "Seattle", ASN: "AS64511", ASOrganization: "Example IPv6 Transit"},
+		},
+		{
+			name:     "IPv4-mapped IPv6 address",
+			address:  "::ffff:203.0.113.10",
+			expected: Location{Country: "JP", City: "Tokyo", ASN: "AS64496", ASOrganization: "Example Networks"},
+		},
+		{
+			name:     "Unknown address",
+			address:  "192.0.2.1",
+			expected: Location{},
+		},
+		{
+			name:     "Service principal",
+			address:  "eks.amazonaws.com",
+			expected: Location{},
+		},
+		{
+			name:     "AWS Internal",
+			address:  "AWS Internal",
+			expected: Location{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.Lookup(tc.address); got != tc.expected {
+				t.Errorf("Lookup(%q) = %+v, want %+v", tc.address, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		name     string
+		city     string
+		asn      string
+		expected string
+	}{
+		{name: "City database only", city: cityDatabase},
+		{name: "ASN database only", asn: asnDatabase},
+		{name: "Swapped databases", city: asnDatabase, asn: cityDatabase, expected: "expected a City or Country database"},
+		{name: "ASN database expected", asn: cityDatabase, expected: "expected an ASN database"},
+		{name: "Missing file", city: filepath.Join("testdata", "missing.mmdb"), expected: "unable to open MaxMind database"},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Open(tc.city, tc.asn)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Open() failed: %v", err)
+				}
+				r.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Open() error = %v, want %q", err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNilResolver(t *testing.T) {
+	r, err := Open("", "")
+	if err != nil || r != nil {
+		t.Fatalf("Open() = %v, %v, want a nil resolver", r, err)
+	}
+	if got := r.Lookup("203.0.113.10"); got != (Location{}) {
+		t.Errorf("Lookup() = %+v, want an empty location", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Command generate writes the City and ASN test databases of the geoip
+// package. Run it with "go generate" from pkg/geoip after changing the records
+// below.
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// cityRecords are the networks of GeoLite2-City-Test.mmdb. Documentation
+// ranges are used so no real address is located.
+var cityRecords = map[string]mmdbtype.Map{
+	"203.0.113.0/24":  city("JP", "Japan", "Tokyo"),
+	"198.51.100.0/24": city("DE", "Germany", ""),
+	"2001:db8::/32":   city("US", "United States", "Seattle"),
+}
+
+// asnRecords are the networks of GeoLite2-ASN-Test.mmdb, using private ASNs
+var asnRecords = map[string]mmdbtype.Map{
+	"203.0.113.0/24": asn(64496, "Example Networks"),
+	"2001:db8::/32":  asn(64511, "Example IPv6 Transit"),
+}
+
+// city builds a City record, without city name when empty
+func city(iso, country, name string) mmdbtype.Map {
+	record := mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String(iso), "names": mmdbtype.Map{"en": mmdbtype.String(country)}},
+	}
+	if name != "" {
+		record["city"] = mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String(name)}}
+	}
+	return record
+}
+
+// asn builds an ASN record
+func asn(number uint32, organization string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(number),
+		"autonomous_system_organization": mmdbtype.String(organization),
+	}
+}
+
+// write creates a database of the given type. Networks are inserted in order
+// so the output is reproducible.
+func write(path, databaseType string, records map[string]mmdbtype.Map) error {
+	// The test addresses live in reserved ranges, which are skipped by default
+	w, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: databaseType, RecordSize: 24, IncludeReservedNetworks: true})
+	if err != nil {
+		return err
+	}
+
+	cidrs := make([]string, 0, len(records))
+	for cidr := range records {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		if err := w.Insert(network, records[cidr]); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = w.WriteTo(f)
+	return err
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: generate <output directory>")
+	}
+	dir := os.Args[1]
+	if err := write(filepath.Join(dir, "GeoLite2-City-Test.mmdb"), "GeoLite2-City", cityRecords); err != nil {
+		log.Fatal(err)
+	}
+	if err := write(filepath.Join(dir, "GeoLite2-ASN-Test.mmdb"), "GeoLite2-ASN", asnRecords); err != nil {
+		log.Fatal(err)
+	}
+}
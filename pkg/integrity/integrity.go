@@ -0,0 +1,425 @@
+package integrity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// References:
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-log-file-validation-digest-file-structure.html
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-log-file-custom-validation.html
+
+// SignatureAlgorithm is the algorithm CloudTrail signs digest files with
+const SignatureAlgorithm = "SHA256withRSA"
+
+// hashAlgorithm is the algorithm digest files record hashes with
+const hashAlgorithm = "SHA-256"
+
+// SignatureSuffix is appended to the name of a digest file to provide its
+// signature, which CloudTrail stores in the S3 object metadata. Only the
+// newest digest file needs one, the others are signed in the next digest file.
+const SignatureSuffix = ".signature"
+
+// Status is the outcome of the validation of a file
+type Status string
+
+const (
+	StatusValid      Status = "VALID"
+	StatusModified   Status = "MODIFIED"
+	StatusInvalid    Status = "INVALID"
+	StatusMissing    Status = "MISSING"
+	StatusUnverified Status = "UNVERIFIED"
+	StatusOutOfChain Status = "OUT-OF-CHAIN"
+)
+
+// statuses lists the statuses in the order they are summarized
+var statuses = []Status{StatusValid, StatusModified, StatusInvalid, StatusMissing, StatusUnverified, StatusOutOfChain}
+
+// Kinds of validated files
+const (
+	KindDigest = "digest"
+	KindLog    = "log"
+)
+
+// Result is the validation outcome of a single file
+type Result struct {
+	Status Status
+	Kind   string
+	File   string
+	Detail string
+}
+
+// Report holds the outcome of the validation of a directory
+type Report struct {
+	Results []Result
+}
+
+// Failures returns the number of files that are missing, modified or wrongly signed
+func (r *Report) Failures() int {
+	failures := 0
+	for _, result := range r.Results {
+		switch result.Status {
+		case StatusModified, StatusInvalid, StatusMissing:
+			failures++
+		}
+	}
+	return failures
+}
+
+// Summary counts the results of each kind of file by status
+func (r *Report) Summary() string {
+	var parts []string
+	for _, kind := range []string{KindDigest, KindLog} {
+		counts := make(map[Status]int)
+		for _, result := range r.Results {
+			if result.Kind == kind {
+				counts[result.Status]++
+			}
+		}
+		var details []string
+		for _, status := range statuses {
+			if counts[status] > 0 {
+				details = append(details, fmt.Sprintf("%d %s", counts[status], strings.ToLower(string(status))))
+			}
+		}
+		if len(details) == 0 {
+			details = append(details, "none")
+		}
+		parts = append(parts, fmt.Sprintf("%s files: %s", kind, strings.Join(details, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// digestLogFile is a log file entry of a digest file
+type digestLogFile struct {
+	S3Bucket      string `json:"s3Bucket"`
+	S3Object      string `json:"s3Object"`
+	HashValue     string `json:"hashValue"`
+	HashAlgorithm string `json:"hashAlgorithm"`
+}
+
+// digest is the content of a digest file. The previous digest fields are null
+// in the first digest file after logging starts.
+type digest struct {
+	AwsAccountId                string          `json:"awsAccountId"`
+	DigestStartTime             string          `json:"digestStartTime"`
+	DigestEndTime               string          `json:"digestEndTime"`
+	DigestS3Bucket              string          `json:"digestS3Bucket"`
+	DigestS3Object              string          `json:"digestS3Object"`
+	DigestPublicKeyFingerprint  string          `json:"digestPublicKeyFingerprint"`
+	DigestSignatureAlgorithm    string          `json:"digestSignatureAlgorithm"`
+	PreviousDigestS3Bucket      string          `json:"previousDigestS3Bucket"`
+	PreviousDigestS3Object      string          `json:"previousDigestS3Object"`
+	PreviousDigestHashValue     string          `json:"previousDigestHashValue"`
+	PreviousDigestHashAlgorithm string          `json:"previousDigestHashAlgorithm"`
+	PreviousDigestSignature     string          `json:"previousDigestSignature"`
+	LogFiles                    []digestLogFile `json:"logFiles"`
+}
+
+// digestFile is a parsed digest file found in the validated directory
+type digestFile struct {
+	path   string
+	rel    string
+	hash   string
+	digest digest
+	status Status
+}
+
+// readFile returns the content of a file, decompressed when gzipped
+func readFile(p string) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// hashHex returns the hex encoded SHA-256 hash of data
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signingString returns the data CloudTrail signs for a digest file
+func signingString(d digest, hash string) string {
+	previousSignature := d.PreviousDigestSignature
+	if previousSignature == "" {
+		previousSignature = "null"
+	}
+	return fmt.Sprintf("%s\n%s/%s\n%s\n%s", d.DigestEndTime, d.DigestS3Bucket, d.DigestS3Object, hash, previousSignature)
+}
+
+// verifySignature checks the signature of a digest file with the public key
+// matching its fingerprint, or any key without fingerprint
+func verifySignature(keys []PublicKey, d digest, hash, signature string) error {
+	if d.DigestSignatureAlgorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", d.DigestSignatureAlgorithm)
+	}
+	sig, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("signature is not hex encoded")
+	}
+
+	var candidates []PublicKey
+	for _, key := range keys {
+		if key.Fingerprint == "" || strings.EqualFold(key.Fingerprint, d.DigestPublicKeyFingerprint) {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no public key with fingerprint %s", d.DigestPublicKeyFingerprint)
+	}
+
+	sum := sha256.Sum256([]byte(signingString(d, hash)))
+	for _, key := range candidates {
+		if rsa.VerifyPKCS1v15(key.Key, crypto.SHA256, sum[:], sig) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match")
+}
+
+// streamOf identifies the digest chain of a trail in a region by the digest
+// file name without its timestamp
+func streamOf(object string) string {
+	name := path.Base(object)
+	if n := strings.LastIndex(name, "_"); n > 0 {
+		return name[:n]
+	}
+	return name
+}
+
+// scanDirectory collects the digest files and log files of a directory,
+// indexing log files by name
+func scanDirectory(dir string) ([]string, map[string][]string, error) {
+	var digests []string
+	logs := make(map[string][]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		switch {
+		case d.IsDir(), strings.HasSuffix(name, SignatureSuffix):
+		case strings.Contains(name, "_CloudTrail-Digest_"):
+			digests = append(digests, p)
+		case strings.Contains(name, "_CloudTrail_"):
+			logs[name] = append(logs[name], p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read %s: %w", dir, err)
+	}
+	return digests, logs, nil
+}
+
+// resolveLogFile returns the downloaded copy of an S3 object, if any
+func resolveLogFile(logs map[string][]string, object string) string {
+	candidates := logs[path.Base(object)]
+	for _, candidate := range candidates {
+		if strings.HasSuffix(filepath.ToSlash(candidate), "/"+object) {
+			return candidate
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return ""
+}
+
+// Validate checks the digest chain and log files downloaded to a directory:
+// the signature of every digest file, the hash each digest file records for
+// the previous one and for its log files, and log files no digest file lists.
+func Validate(dir string, keys []PublicKey) (*Report, error) {
+	digestPaths, logs, err := scanDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	relative := func(p string) string {
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return p
+	}
+
+	report := &Report{}
+	var digests []*digestFile
+	for _, p := range digestPaths {
+		data, err := readFile(p)
+		if err == nil {
+			d := &digestFile{path: p, rel: relative(p), hash: hashHex(data)}
+			if err = json.Unmarshal(data, &d.digest); err == nil {
+				digests = append(digests, d)
+				continue
+			}
+		}
+		report.Results = append(report.Results, Result{
+			Status: StatusInvalid,
+			Kind:   KindDigest,
+			File:   relative(p),
+			Detail: fmt.Sprintf("unable to read digest file: %v", err),
+		})
+	}
+	sort.Slice(digests, func(a, b int) bool {
+		if digests[a].digest.DigestEndTime != digests[b].digest.DigestEndTime {
+			return digests[a].digest.DigestEndTime < digests[b].digest.DigestEndTime
+		}
+		return digests[a].rel < digests[b].rel
+	})
+
+	byObject := make(map[string]*digestFile)
+	successors := make(map[string]*digestFile)
+	oldest := make(map[string]string)
+	for _, d := range digests {
+		byObject[d.digest.DigestS3Object] = d
+		if d.digest.PreviousDigestS3Object != "" {
+			successors[d.digest.PreviousDigestS3Object] = d
+		}
+		stream := streamOf(d.digest.DigestS3Object)
+		if start, ok := oldest[stream]; !ok || d.digest.DigestStartTime < start {
+			oldest[stream] = d.digest.DigestStartTime
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, d := range digests {
+		report.Results = append(report.Results, validateDigest(d, successors[d.digest.DigestS3Object], keys))
+
+		// A previous digest file is only expected when older ones of the chain were downloaded
+		previous := d.digest.PreviousDigestS3Object
+		if previous != "" && byObject[previous] == nil && oldest[streamOf(d.digest.DigestS3Object)] < d.digest.DigestStartTime {
+			report.Results = append(report.Results, Result{
+				Status: StatusMissing,
+				Kind:   KindDigest,
+				File:   previous,
+				Detail: "previous digest file of " + d.rel,
+			})
+		}
+
+		for _, logFile := range d.digest.LogFiles {
+			p := resolveLogFile(logs, logFile.S3Object)
+			if p == "" {
+				report.Results = append(report.Results, Result{
+					Status: StatusMissing,
+					Kind:   KindLog,
+					File:   logFile.S3Object,
+					Detail: "listed in " + d.rel,
+				})
+				continue
+			}
+			referenced[p] = true
+			report.Results = append(report.Results, validateLogFile(d, logFile, p, relative(p)))
+		}
+	}
+
+	var unreferenced []string
+	for _, paths := range logs {
+		for _, p := range paths {
+			if !referenced[p] {
+				unreferenced = append(unreferenced, relative(p))
+			}
+		}
+	}
+	sort.Strings(unreferenced)
+	for _, rel := range unreferenced {
+		report.Results = append(report.Results, Result{
+			Status: StatusOutOfChain,
+			Kind:   KindLog,
+			File:   rel,
+			Detail: "not listed in any digest file",
+		})
+	}
+
+	return report, nil
+}
+
+// validateDigest checks a digest file against the next digest file of its
+// chain, or against its signature file when it is the newest one
+func validateDigest(d, successor *digestFile, keys []PublicKey) Result {
+	result := Result{Kind: KindDigest, File: d.rel}
+
+	var signature string
+	if successor != nil {
+		if !strings.EqualFold(successor.digest.PreviousDigestHashValue, d.hash) {
+			d.status = StatusModified
+			result.Status = d.status
+			result.Detail = "hash does not match the one recorded in " + successor.rel
+			return result
+		}
+		signature = successor.digest.PreviousDigestSignature
+	} else if data, err := os.ReadFile(d.path + SignatureSuffix); err == nil {
+		signature = string(data)
+	}
+
+	if strings.TrimSpace(signature) == "" {
+		d.status = StatusUnverified
+		result.Status = d.status
+		result.Detail = "newest digest file, save the signature from its S3 object metadata as " + filepath.Base(d.path) + SignatureSuffix
+		return result
+	}
+
+	if err := verifySignature(keys, d.digest, d.hash, signature); err != nil {
+		d.status = StatusInvalid
+		result.Status = d.status
+		result.Detail = err.Error()
+		return result
+	}
+
+	d.status = StatusValid
+	result.Status = d.status
+	return result
+}
+
+// validateLogFile checks a log file against the hash recorded in its digest file
+func validateLogFile(d *digestFile, logFile digestLogFile, p, rel string) Result {
+	result := Result{Kind: KindLog, File: rel}
+
+	if logFile.HashAlgorithm != "" && logFile.HashAlgorithm != hashAlgorithm {
+		result.Status = StatusInvalid
+		result.Detail = fmt.Sprintf("unsupported hash algorithm %q in %s", logFile.HashAlgorithm, d.rel)
+		return result
+	}
+
+	data, err := readFile(p)
+	if err != nil {
+		result.Status = StatusInvalid
+		result.Detail = fmt.Sprintf("unable to read log file: %v", err)
+		return result
+	}
+
+	switch {
+	case !strings.EqualFold(hashHex(data), logFile.HashValue):
+		result.Status = StatusModified
+		result.Detail = "hash does not match the one recorded in " + d.rel
+	case d.status != StatusValid:
+		// The recorded hash can only be trusted once its digest file is
+		result.Status = StatusUnverified
+		result.Detail = "listed in " + d.rel + ", which could not be verified"
+	default:
+		result.Status = StatusValid
+	}
+	return result
+}
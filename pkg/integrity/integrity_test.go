@@ -0,0 +1,342 @@
+package integrity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testBucket      = "trail-bucket"
+	testFingerprint = "8eba5db5bea9b640d1b9aca8c4c5e3a2"
+)
+
+// testChain is a digest chain written to a temporary directory
+type testChain struct {
+	dir     string
+	digests []string
+	logs    []string
+}
+
+func writeGzip(t *testing.T, p string, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, d digest, hash string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(signingString(d, hash)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+// newTestChain writes hourly digest files each listing one log file, and the
+// signature file of the newest digest file
+func newTestChain(t *testing.T, key *rsa.PrivateKey, hours int) *testChain {
+	t.Helper()
+	chain := &testChain{dir: t.TempDir()}
+	start := time.Date(2025, 5, 12, 0, 0, 0, 0, time.UTC)
+
+	var previous digest
+	var previousHash, previousSignature string
+	for n := 0; n < hours; n++ {
+		begin := start.Add(time.Duration(n) * time.Hour)
+		end := begin.Add(time.Hour)
+
+		logObject := fmt.Sprintf("AWSLogs/123456789012/CloudTrail/us-east-1/2025/05/12/123456789012_CloudTrail_us-east-1_%s_a%d.json.gz", begin.Format("20060102T1504Z"), n)
+		logData := []byte(fmt.Sprintf(`{"Records":[{"eventID":"event-%d"}]}`, n))
+		logPath := filepath.Join(chain.dir, filepath.FromSlash(logObject))
+		writeGzip(t, logPath, logData)
+		chain.logs = append(chain.logs, logPath)
+
+		d := digest{
+			AwsAccountId:               "123456789012",
+			DigestStartTime:            begin.Format(time.RFC3339),
+			DigestEndTime:              end.Format(time.RFC3339),
+			DigestS3Bucket:             testBucket,
+			DigestS3Object:             fmt.Sprintf("AWSLogs/123456789012/CloudTrail-Digest/us-east-1/2025/05/12/123456789012_CloudTrail-Digest_us-east-1_trail_us-east-1_%s.json.gz", end.Format("20060102T150405Z")),
+			DigestPublicKeyFingerprint: testFingerprint,
+			DigestSignatureAlgorithm:   SignatureAlgorithm,
+			LogFiles: []digestLogFile{{
+				S3Bucket:      testBucket,
+				S3Object:      logObject,
+				HashValue:     hashHex(logData),
+				HashAlgorithm: hashAlgorithm,
+			}},
+		}
+		if n > 0 {
+			d.PreviousDigestS3Bucket = testBucket
+			d.PreviousDigestS3Object = previous.DigestS3Object
+			d.PreviousDigestHashValue = previousHash
+			d.PreviousDigestHashAlgorithm = hashAlgorithm
+			d.PreviousDigestSignature = previousSignature
+		}
+
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digestPath := filepath.Join(chain.dir, filepath.FromSlash(d.DigestS3Object))
+		writeGzip(t, digestPath, data)
+		chain.digests = append(chain.digests, digestPath)
+
+		previous, previousHash = d, hashHex(data)
+		previousSignature = sign(t, key, d, previousHash)
+	}
+
+	newest := chain.digests[len(chain.digests)-1]
+	if err := os.WriteFile(newest+SignatureSuffix, []byte(previousSignature+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return chain
+}
+
+func (c *testChain) rel(p string) string {
+	rel, _ := filepath.Rel(c.dir, p)
+	return filepath.ToSlash(rel)
+}
+
+func statusesOf(report *Report, kind string) []Status {
+	var result []Status
+	for _, r := range report.Results {
+		if r.Kind == kind {
+			result = append(result, r.Status)
+		}
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name            string
+		keys            []PublicKey
+		tamper          func(t *testing.T, c *testChain)
+		expectedDigests []Status
+		expectedLogs    []Status
+		expectedFailed  int
+	}{
+		{
+			name:            "Intact chain",
+			keys:            []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusValid, StatusValid, StatusValid},
+		},
+		{
+			name:            "Key without fingerprint",
+			keys:            []PublicKey{{Key: &otherKey.PublicKey}, {Key: &key.PublicKey}},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusValid, StatusValid, StatusValid},
+		},
+		{
+			name:            "Wrong public key",
+			keys:            []PublicKey{{Fingerprint: testFingerprint, Key: &otherKey.PublicKey}},
+			expectedDigests: []Status{StatusInvalid, StatusInvalid, StatusInvalid},
+			expectedLogs:    []Status{StatusUnverified, StatusUnverified, StatusUnverified},
+			expectedFailed:  3,
+		},
+		{
+			name: "Modified log file",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				writeGzip(t, c.logs[1], []byte(`{"Records":[]}`))
+			},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusValid, StatusModified, StatusValid},
+			expectedFailed:  1,
+		},
+		{
+			name: "Deleted log file",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				os.Remove(c.logs[0])
+			},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusMissing, StatusValid, StatusValid},
+			expectedFailed:  1,
+		},
+		{
+			name: "Modified digest file",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				// Drop the log file from the digest file
+				data, _ := readFile(c.digests[0])
+				var d digest
+				json.Unmarshal(data, &d)
+				d.LogFiles = nil
+				data, _ = json.Marshal(d)
+				writeGzip(t, c.digests[0], data)
+			},
+			expectedDigests: []Status{StatusModified, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusValid, StatusValid, StatusOutOfChain},
+			expectedFailed:  1,
+		},
+		{
+			name: "Deleted digest file in the middle of the chain",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				os.Remove(c.digests[1])
+			},
+			expectedDigests: []Status{StatusUnverified, StatusValid, StatusMissing},
+			expectedLogs:    []Status{StatusUnverified, StatusValid, StatusOutOfChain},
+			expectedFailed:  1,
+		},
+		{
+			name: "Newest digest file without signature",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				os.Remove(c.digests[2] + SignatureSuffix)
+			},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusUnverified},
+			expectedLogs:    []Status{StatusValid, StatusValid, StatusUnverified},
+		},
+		{
+			name: "Log file not listed in any digest file",
+			keys: []PublicKey{{Fingerprint: testFingerprint, Key: &key.PublicKey}},
+			tamper: func(t *testing.T, c *testChain) {
+				writeGzip(t, filepath.Join(c.dir, "AWSLogs/123456789012/CloudTrail/us-east-1/2025/05/12/123456789012_CloudTrail_us-east-1_20250512T0330Z_x.json.gz"), []byte(`{"Records":[]}`))
+			},
+			expectedDigests: []Status{StatusValid, StatusValid, StatusValid},
+			expectedLogs:    []Status{StatusValid, StatusValid, StatusValid, StatusOutOfChain},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newTestChain(t, key, 3)
+			if tc.tamper != nil {
+				tc.tamper(t, chain)
+			}
+
+			report, err := Validate(chain.dir, tc.keys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := statusesOf(report, KindDigest); !reflect.DeepEqual(got, tc.expectedDigests) {
+				t.Errorf("digest statuses = %v, want %v\n%+v", got, tc.expectedDigests, report.Results)
+			}
+			if got := statusesOf(report, KindLog); !reflect.DeepEqual(got, tc.expectedLogs) {
+				t.Errorf("log statuses = %v, want %v\n%+v", got, tc.expectedLogs, report.Results)
+			}
+			if got := report.Failures(); got != tc.expectedFailed {
+				t.Errorf("Failures() = %d, want %d", got, tc.expectedFailed)
+			}
+		})
+	}
+}
+
+func TestValidateChainStart(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := newTestChain(t, key, 3)
+
+	// Digest files older than the downloaded range are not reported missing
+	os.Remove(chain.digests[0])
+	os.Remove(chain.logs[0])
+
+	report, err := Validate(chain.dir, []PublicKey{{Key: &key.PublicKey}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Failures() != 0 {
+		t.Errorf("expected no failures, got %+v", report.Results)
+	}
+	if report.Results[0].File != chain.rel(chain.digests[1]) {
+		t.Errorf("expected results to start with the oldest digest file, got %+v", report.Results[0])
+	}
+	expected := "digest files: 2 valid; log files: 2 valid"
+	if got := report.Summary(); got != expected {
+		t.Errorf("Summary() = %q, want %q", got, expected)
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := json.Marshal(map[string]interface{}{
+		"PublicKeyList": []map[string]interface{}{
+			{"Value": pkcs1, "Fingerprint": strings.ToUpper(testFingerprint), "ValidityStartTime": "2025-01-01T00:00:00+00:00"},
+		},
+	})
+
+	testCases := []struct {
+		name                string
+		data                []byte
+		expectedFingerprint string
+		expectError         bool
+	}{
+		{"list-public-keys output", list, testFingerprint, false},
+		{"PKCS #1 PEM", pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: pkcs1}), "", false},
+		{"PKIX PEM", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}), "", false},
+		{"DER", pkcs1, "", false},
+		{"Empty key list", []byte(`{"PublicKeyList": []}`), "", true},
+		{"Not a key", []byte("not a key"), "", true},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := ParsePublicKeys(tc.data)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(keys) != 1 || keys[0].Fingerprint != tc.expectedFingerprint || !keys[0].Key.Equal(&key.PublicKey) {
+				t.Errorf("unexpected keys: %+v", keys)
+			}
+		})
+	}
+}
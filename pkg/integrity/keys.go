@@ -0,0 +1,107 @@
+package integrity
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PublicKey is a key CloudTrail signs digest files with. Keys loaded from PEM
+// or DER files have no fingerprint and are tried for every digest file.
+type PublicKey struct {
+	Fingerprint string
+	Key         *rsa.PublicKey
+}
+
+// publicKeyList is the output of "aws cloudtrail list-public-keys"
+type publicKeyList struct {
+	PublicKeyList []struct {
+		Value       []byte `json:"Value"`
+		Fingerprint string `json:"Fingerprint"`
+	} `json:"PublicKeyList"`
+}
+
+// LoadPublicKeys reads the public keys of a file
+func LoadPublicKeys(path string) ([]PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public keys from %s: %w", path, err)
+	}
+	keys, err := ParsePublicKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public keys from %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParsePublicKeys parses the output of "aws cloudtrail list-public-keys", PEM
+// encoded keys or a single DER encoded key
+func ParsePublicKeys(data []byte) ([]PublicKey, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		var list publicKeyList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		var keys []PublicKey
+		for _, entry := range list.PublicKeyList {
+			key, err := parseRSAPublicKey(entry.Value)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", entry.Fingerprint, err)
+			}
+			keys = append(keys, PublicKey{Fingerprint: strings.ToLower(entry.Fingerprint), Key: key})
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no public key found")
+		}
+		return keys, nil
+	}
+
+	if bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		var keys []PublicKey
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			key, err := parseRSAPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, PublicKey{Key: key})
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no public key found")
+		}
+		return keys, nil
+	}
+
+	key, err := parseRSAPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return []PublicKey{{Key: key}}, nil
+}
+
+// parseRSAPublicKey parses a PKCS #1 (as served by CloudTrail) or PKIX encoded RSA public key
+func parseRSAPublicKey(der []byte) (*rsa.PublicKey, error) {
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,231 @@
+package iprange
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// amazonService is the catch-all service ip-ranges.json lists every prefix under
+const amazonService = "AMAZON"
+
+// Tag is what is known about an IP address. Fields without a match are empty.
+type Tag struct {
+	Service string
+	Region  string
+	Label   string
+}
+
+// awsRanges is the subset of https://ip-ranges.amazonaws.com/ip-ranges.json in use
+type awsRanges struct {
+	Prefixes []struct {
+		IPPrefix string `json:"ip_prefix"`
+		Region   string `json:"region"`
+		Service  string `json:"service"`
+	} `json:"prefixes"`
+	IPv6Prefixes []struct {
+		IPv6Prefix string `json:"ipv6_prefix"`
+		Region     string `json:"region"`
+		Service    string `json:"service"`
+	} `json:"ipv6_prefixes"`
+}
+
+// prefixTable finds the most specific prefix containing an address
+type prefixTable struct {
+	values  map[netip.Prefix]Tag
+	lengths []int
+}
+
+func newPrefixTable() *prefixTable {
+	return &prefixTable{values: make(map[netip.Prefix]Tag)}
+}
+
+// get returns the value stored for a prefix
+func (t *prefixTable) get(prefix netip.Prefix) (Tag, bool) {
+	tag, ok := t.values[prefix.Masked()]
+	return tag, ok
+}
+
+// set stores the value of a prefix
+func (t *prefixTable) set(prefix netip.Prefix, tag Tag) {
+	prefix = prefix.Masked()
+	if _, ok := t.values[prefix]; !ok {
+		idx := sort.Search(len(t.lengths), func(i int) bool { return t.lengths[i] <= prefix.Bits() })
+		if idx == len(t.lengths) || t.lengths[idx] != prefix.Bits() {
+			t.lengths = append(t.lengths[:idx], append([]int{prefix.Bits()}, t.lengths[idx:]...)...)
+		}
+	}
+	t.values[prefix] = tag
+}
+
+// lookup returns the value of the longest prefix containing the address
+func (t *prefixTable) lookup(ip netip.Addr) (Tag, bool) {
+	for _, bits := range t.lengths {
+		if bits > ip.BitLen() {
+			continue
+		}
+		prefix, err := ip.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if tag, ok := t.values[prefix]; ok {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+// Tagger tags IP addresses with the AWS service and region they belong to and
+// with user-supplied labels. Results are cached per address. A Tagger is safe
+// for concurrent use; a nil Tagger tags nothing.
+type Tagger struct {
+	aws    *prefixTable
+	labels *prefixTable
+	mu     sync.Mutex
+	cache  map[string]Tag
+}
+
+// New creates a tagger from a local copy of ip-ranges.json and label files.
+// Both are optional; without any the returned Tagger is nil.
+func New(awsRangesPath string, labelPaths []string) (*Tagger, error) {
+	if awsRangesPath == "" && len(labelPaths) == 0 {
+		return nil, nil
+	}
+
+	t := &Tagger{aws: newPrefixTable(), labels: newPrefixTable(), cache: make(map[string]Tag)}
+	if awsRangesPath != "" {
+		if err := t.loadAWSRanges(awsRangesPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range labelPaths {
+		if err := t.loadLabels(path); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// addAWSRange records a prefix. A prefix is listed once per service, always
+// including the AMAZON catch-all, so the specific service is kept.
+func (t *Tagger) addAWSRange(cidr, service, region string) error {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	if existing, ok := t.aws.get(prefix); ok && service == amazonService && existing.Service != amazonService {
+		return nil
+	}
+	t.aws.set(prefix, Tag{Service: service, Region: region})
+	return nil
+}
+
+// loadAWSRanges reads ip-ranges.json
+func (t *Tagger) loadAWSRanges(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read AWS IP ranges: %w", err)
+	}
+	var ranges awsRanges
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return fmt.Errorf("invalid AWS IP ranges file %s: %w", path, err)
+	}
+
+	for _, p := range ranges.Prefixes {
+		if err := t.addAWSRange(p.IPPrefix, p.Service, p.Region); err != nil {
+			return fmt.Errorf("invalid AWS IP ranges file %s: %w", path, err)
+		}
+	}
+	for _, p := range ranges.IPv6Prefixes {
+		if err := t.addAWSRange(p.IPv6Prefix, p.Service, p.Region); err != nil {
+			return fmt.Errorf("invalid AWS IP ranges file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// parseCIDR accepts a CIDR block or a single address
+func parseCIDR(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// loadLabels reads a label file: one "CIDR label" pair per line, separated by
+// spaces or tabs, where the label is the rest of the line. Blank lines and
+// lines starting with "#" are ignored. Later files win over earlier ones for
+// the same CIDR.
+func (t *Tagger) loadLabels(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to read IP labels: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		cidr, label := text, ""
+		if idx := strings.IndexFunc(text, unicode.IsSpace); idx >= 0 {
+			cidr, label = text[:idx], strings.TrimSpace(text[idx:])
+		}
+		prefix, err := parseCIDR(cidr)
+		if err != nil || label == "" {
+			return fmt.Errorf("invalid IP label at %s:%d: expected a CIDR block followed by a label", path, line)
+		}
+		t.labels.set(prefix, Tag{Label: label})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read IP labels %s: %w", path, err)
+	}
+	return nil
+}
+
+// HasLabels reports whether any label was loaded
+func (t *Tagger) HasLabels() bool {
+	return t != nil && len(t.labels.values) > 0
+}
+
+// Lookup tags an address. Values that are not IP addresses, such as the
+// service principals CloudTrail reports for calls made by AWS services, get an
+// empty Tag.
+func (t *Tagger) Lookup(address string) Tag {
+	if t == nil {
+		return Tag{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tag, ok := t.cache[address]; ok {
+		return tag
+	}
+
+	var tag Tag
+	if ip, err := netip.ParseAddr(address); err == nil {
+		ip = ip.Unmap()
+		tag, _ = t.aws.lookup(ip)
+		if label, ok := t.labels.lookup(ip); ok {
+			tag.Label = label.Label
+		}
+	}
+	t.cache[address] = tag
+	return tag
+}
+
+// Known reports whether an address matches a user-supplied label
+func (t *Tagger) Known(address string) bool {
+	return t.Lookup(address).Label != ""
+}
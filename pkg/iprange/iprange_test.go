@@ -0,0 +1,156 @@
+package iprange
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	awsRangesFile = filepath.Join("testdata", "ip-ranges.json")
+	labelsFile    = filepath.Join("testdata", "labels.txt")
+)
+
+func TestLookup(t *testing.T) {
+	tagger, err := New(awsRangesFile, []string{labelsFile})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		address  string
+		expected Tag
+	}{
+		{
+			name:     "Specific service over the AMAZON catch-all",
+			address:  "3.5.141.7",
+			expected: Tag{Service: "S3", Region: "ap-northeast-2"},
+		},
+		{
+			name:     "Specific service listed before the catch-all",
+			address:  "52.94.77.1",
+			expected: Tag{Service: "EC2", Region: "us-west-2"},
+		},
+		{
+			name:     "Most specific prefix",
+			address:  "52.94.1.1",
+			expected: Tag{Service: "AMAZON", Region: "GLOBAL"},
+		},
+		{
+			name:     "IPv6 address",
+			address:  "2600:1f14:fff:f800::1",
+			expected: Tag{Service: "EC2", Region: "us-west-2"},
+		},
+		{
+			name:     "Label",
+			address:  "198.51.100.42",
+			expected: Tag{Label: "office-vpn"},
+		},
+		{
+			name:     "Label on an AWS address",
+			address:  "52.94.76.10",
+			expected: Tag{Service: "EC2", Region: "us-west-2", Label: "ci runner (nat gateway)"},
+		},
+		{
+			name:     "Tab-separated label",
+			address:  "10.20.3.4",
+			expected: Tag{Label: "build farm"},
+		},
+		{
+			name:     "IPv4-mapped IPv6 address",
+			address:  "::ffff:198.51.100.42",
+			expected: Tag{Label: "office-vpn"},
+		},
+		{
+			name:     "Unknown address",
+			address:  "203.0.113.10",
+			expected: Tag{},
+		},
+		{
+			name:     "Service principal",
+			address:  "eks.amazonaws.com",
+			expected: Tag{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tagger.Lookup(tc.address); got != tc.expected {
+				t.Errorf("Lookup(%q) = %+v, want %+v", tc.address, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestKnown(t *testing.T) {
+	tagger, err := New(awsRangesFile, []string{labelsFile})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !tagger.HasLabels() {
+		t.Error("HasLabels() = false, want true")
+	}
+	if !tagger.Known("198.51.100.1") {
+		t.Error("Known() = false for a labelled address")
+	}
+	if tagger.Known("3.5.141.7") {
+		t.Error("Known() = true for an address only in the AWS ranges")
+	}
+
+	var nilTagger *Tagger
+	if nilTagger.HasLabels() || nilTagger.Known("198.51.100.1") || nilTagger.Lookup("198.51.100.1") != (Tag{}) {
+		t.Error("nil Tagger should tag nothing")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	testCases := []struct {
+		name      string
+		awsRanges string
+		labels    []string
+		expected  string
+	}{
+		{
+			name:      "Missing AWS ranges",
+			awsRanges: filepath.Join(dir, "missing.json"),
+			expected:  "unable to read AWS IP ranges",
+		},
+		{
+			name:      "Invalid AWS ranges",
+			awsRanges: write("bad.json", `{"prefixes": [{"ip_prefix": "3.5.140.0/33"}]}`),
+			expected:  "invalid AWS IP ranges file",
+		},
+		{
+			name:     "Label without CIDR",
+			labels:   []string{write("bad-cidr.txt", "office-vpn\n")},
+			expected: "bad-cidr.txt:1",
+		},
+		{
+			name:     "CIDR without label",
+			labels:   []string{write("bad-label.txt", "# comment\n\n198.51.100.0/24\n")},
+			expected: "bad-label.txt:3",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.awsRanges, tc.labels)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("New() error = %v, want %q", err, tc.expected)
+			}
+		})
+	}
+}
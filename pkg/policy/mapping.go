@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"regexp"
+	"strings"
+)
+
+// References:
+// - https://docs.aws.amazon.com/service-authorization/latest/reference/reference_policies_actions-resources-contextkeys.html
+// - https://docs.aws.amazon.com/IAM/latest/UserGuide/access-analyzer-policy-generation.html
+
+// servicePrefixes maps event sources whose IAM service prefix differs from the
+// host name of the event source
+var servicePrefixes = map[string]string{
+	"monitoring.amazonaws.com":        "cloudwatch",
+	"email.amazonaws.com":             "ses",
+	"runtime.sagemaker.amazonaws.com": "sagemaker",
+	"api.ecr.amazonaws.com":           "ecr",
+	"api.mediatailor.amazonaws.com":   "mediatailor",
+	"bedrock-runtime.amazonaws.com":   "bedrock",
+	"tagging.amazonaws.com":           "tag",
+	"streams.dynamodb.amazonaws.com":  "dynamodb",
+}
+
+// actionOverrides maps "eventSource:eventName" pairs whose IAM action has a
+// different name. An empty list means the call needs no IAM permission.
+var actionOverrides = map[string][]string{
+	// Calls that are not authorized through IAM policies
+	"sts.amazonaws.com:GetCallerIdentity":         {},
+	"sts.amazonaws.com:AssumeRoleWithSAML":        {},
+	"sts.amazonaws.com:AssumeRoleWithWebIdentity": {},
+
+	// Amazon S3
+	"s3.amazonaws.com:ListBuckets":             {"s3:ListAllMyBuckets"},
+	"s3.amazonaws.com:ListObjects":             {"s3:ListBucket"},
+	"s3.amazonaws.com:ListObjectsV2":           {"s3:ListBucket"},
+	"s3.amazonaws.com:HeadBucket":              {"s3:ListBucket"},
+	"s3.amazonaws.com:HeadObject":              {"s3:GetObject"},
+	"s3.amazonaws.com:ListObjectVersions":      {"s3:ListBucketVersions"},
+	"s3.amazonaws.com:ListMultipartUploads":    {"s3:ListBucketMultipartUploads"},
+	"s3.amazonaws.com:ListParts":               {"s3:ListMultipartUploadParts"},
+	"s3.amazonaws.com:CreateMultipartUpload":   {"s3:PutObject"},
+	"s3.amazonaws.com:UploadPart":              {"s3:PutObject"},
+	"s3.amazonaws.com:UploadPartCopy":          {"s3:PutObject", "s3:GetObject"},
+	"s3.amazonaws.com:CompleteMultipartUpload": {"s3:PutObject"},
+	"s3.amazonaws.com:CopyObject":              {"s3:PutObject", "s3:GetObject"},
+	"s3.amazonaws.com:DeleteObjects":           {"s3:DeleteObject"},
+	"s3.amazonaws.com:GetBucketEncryption":     {"s3:GetEncryptionConfiguration"},
+	"s3.amazonaws.com:PutBucketEncryption":     {"s3:PutEncryptionConfiguration"},
+	"s3.amazonaws.com:DeleteBucketEncryption":  {"s3:PutEncryptionConfiguration"},
+	"s3.amazonaws.com:GetBucketLifecycle":      {"s3:GetLifecycleConfiguration"},
+	"s3.amazonaws.com:PutBucketLifecycle":      {"s3:PutLifecycleConfiguration"},
+	"s3.amazonaws.com:DeleteBucketLifecycle":   {"s3:PutLifecycleConfiguration"},
+	"s3.amazonaws.com:GetBucketReplication":    {"s3:GetReplicationConfiguration"},
+	"s3.amazonaws.com:PutBucketReplication":    {"s3:PutReplicationConfiguration"},
+	"s3.amazonaws.com:DeleteBucketReplication": {"s3:PutReplicationConfiguration"},
+	"s3.amazonaws.com:DeleteBucketCors":        {"s3:PutBucketCORS"},
+	"s3.amazonaws.com:GetBucketCors":           {"s3:GetBucketCORS"},
+	"s3.amazonaws.com:PutBucketCors":           {"s3:PutBucketCORS"},
+	"s3.amazonaws.com:DeleteBucketTagging":     {"s3:PutBucketTagging"},
+
+	// AWS Lambda
+	"lambda.amazonaws.com:Invoke": {"lambda:InvokeFunction"},
+
+	// AWS Key Management Service
+	"kms.amazonaws.com:ReEncrypt": {"kms:ReEncryptFrom", "kms:ReEncryptTo"},
+}
+
+// resourceParameters maps IAM actions, or a whole service prefix, to the
+// request parameters naming the resource the action is authorized on. Values
+// that are not ARNs, such as key aliases or function names, are not resolved.
+// An action entry takes precedence over the entry of its service.
+var resourceParameters = map[string][]string{
+	// AWS Key Management Service
+	"kms":               {"keyId"},
+	"kms:ReEncryptFrom": {"sourceKeyId"},
+	"kms:ReEncryptTo":   {"destinationKeyId"},
+
+	// AWS Lambda
+	"lambda": {"functionName"},
+
+	// AWS Secrets Manager
+	"secretsmanager": {"secretId"},
+
+	// Amazon SNS
+	"sns": {"topicArn"},
+
+	// AWS Step Functions
+	"states": {"stateMachineArn"},
+
+	// AWS Security Token Service
+	"sts:AssumeRole": {"roleArn"},
+}
+
+// unauthorizedSources are event sources whose events never map to IAM actions
+var unauthorizedSources = map[string]bool{
+	"signin.amazonaws.com":    true,
+	"sso-oauth.amazonaws.com": true,
+}
+
+// lambdaVersionSuffix matches API version suffixes Lambda appends to event names
+// (e.g. "GetFunction20150331v2")
+var lambdaVersionSuffix = regexp.MustCompile(`\d{8}(v\d+)?$`)
+
+// servicePrefix returns the IAM service prefix of an event source
+func servicePrefix(eventSource string) string {
+	if prefix, ok := servicePrefixes[eventSource]; ok {
+		return prefix
+	}
+	return strings.TrimSuffix(eventSource, ".amazonaws.com")
+}
+
+// Actions maps a CloudTrail event to the IAM actions required to perform it.
+// The boolean result is false when the event source is not an AWS service.
+func Actions(eventSource, eventName string) ([]string, bool) {
+	if !strings.HasSuffix(eventSource, ".amazonaws.com") || eventName == "" {
+		return nil, false
+	}
+	if unauthorizedSources[eventSource] {
+		return nil, true
+	}
+
+	if eventSource == "lambda.amazonaws.com" {
+		eventName = lambdaVersionSuffix.ReplaceAllString(eventName, "")
+	}
+
+	if actions, ok := actionOverrides[eventSource+":"+eventName]; ok {
+		return actions, true
+	}
+
+	// API Gateway management permissions are expressed as HTTP verbs
+	if eventSource == "apigateway.amazonaws.com" {
+		return []string{"apigateway:" + apiGatewayVerb(eventName)}, true
+	}
+	return []string{servicePrefix(eventSource) + ":" + eventName}, true
+}
+
+// apiGatewayVerb returns the HTTP verb API Gateway authorizes a management call with
+func apiGatewayVerb(eventName string) string {
+	switch {
+	case strings.HasPrefix(eventName, "Get"):
+		return "GET"
+	case strings.HasPrefix(eventName, "Create"), strings.HasPrefix(eventName, "Import"):
+		return "POST"
+	case strings.HasPrefix(eventName, "Put"):
+		return "PUT"
+	case strings.HasPrefix(eventName, "Delete"):
+		return "DELETE"
+	default:
+		return "PATCH"
+	}
+}
@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// PolicyVersion is the current IAM policy language version
+const PolicyVersion = "2012-10-17"
+
+// Statement is a single IAM policy statement. Resource is either "*" or a list of ARNs.
+type Statement struct {
+	Sid      string      `json:"Sid"`
+	Effect   string      `json:"Effect"`
+	Action   []string    `json:"Action"`
+	Resource interface{} `json:"Resource"`
+}
+
+// Document is an IAM policy document
+type Document struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Generator accumulates the actions observed in successful events
+type Generator struct {
+	includeResources bool
+	actions          map[string]map[string]bool // action -> resource ARNs
+	unmapped         map[string]bool
+}
+
+// NewGenerator creates a generator, optionally scoping actions to the resources they touched
+func NewGenerator(includeResources bool) *Generator {
+	return &Generator{
+		includeResources: includeResources,
+		actions:          make(map[string]map[string]bool),
+		unmapped:         make(map[string]bool),
+	}
+}
+
+// Add records the IAM actions required by a successful event
+func (g *Generator) Add(e *types.CloudTrailEvent) {
+	if e.ErrorCode != "" {
+		return
+	}
+
+	actions, ok := Actions(e.EventSource, e.EventName)
+	if !ok {
+		g.unmapped[e.EventSource+":"+e.EventName] = true
+		return
+	}
+
+	for _, action := range actions {
+		var resources []string
+		if g.includeResources {
+			resources = resourceArns(e, action)
+		}
+
+		if g.actions[action] == nil {
+			g.actions[action] = make(map[string]bool)
+		}
+		for _, arn := range resources {
+			g.actions[action][arn] = true
+		}
+		// Any call without an identifiable resource widens the action to "*"
+		if len(resources) == 0 {
+			g.actions[action]["*"] = true
+		}
+	}
+}
+
+// Unmapped returns the eventSource:eventName pairs that could not be mapped to IAM actions
+func (g *Generator) Unmapped() []string {
+	unmapped := make([]string, 0, len(g.unmapped))
+	for pair := range g.unmapped {
+		unmapped = append(unmapped, pair)
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+// Document builds the policy, with one statement per service and resource set
+func (g *Generator) Document() Document {
+	type group struct {
+		service   string
+		resources []string
+		actions   []string
+	}
+
+	groups := make(map[string]*group)
+	for action, arns := range g.actions {
+		service := strings.SplitN(action, ":", 2)[0]
+
+		var resources []string
+		if arns["*"] {
+			resources = []string{"*"}
+		} else {
+			for arn := range arns {
+				resources = append(resources, arn)
+			}
+			sort.Strings(resources)
+		}
+
+		key := service + "\x00" + strings.Join(resources, "\x00")
+		if groups[key] == nil {
+			groups[key] = &group{service: service, resources: resources}
+		}
+		groups[key].actions = append(groups[key].actions, action)
+	}
+
+	keys := make([]string, 0, len(groups))
+	perService := make(map[string]int)
+	for key, grp := range groups {
+		keys = append(keys, key)
+		perService[grp.service]++
+	}
+	sort.Strings(keys)
+
+	doc := Document{Version: PolicyVersion, Statement: []Statement{}}
+	seen := make(map[string]int)
+	for _, key := range keys {
+		grp := groups[key]
+		sort.Strings(grp.actions)
+
+		sid := statementId(grp.service)
+		seen[grp.service]++
+		if perService[grp.service] > 1 {
+			sid = fmt.Sprintf("%sGroup%d", sid, seen[grp.service])
+		}
+
+		var resource interface{} = grp.resources
+		if len(grp.resources) == 1 && grp.resources[0] == "*" {
+			resource = "*"
+		}
+
+		doc.Statement = append(doc.Statement, Statement{
+			Sid:      sid,
+			Effect:   "Allow",
+			Action:   grp.actions,
+			Resource: resource,
+		})
+	}
+	return doc
+}
+
+// statementId converts a service prefix such as "cognito-idp" into an alphanumeric Sid
+func statementId(service string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range service {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// resourceArns returns the ARNs of the resources an action of the event was
+// authorized on. Only the parameters known to name that resource are read:
+// other parameters hold ARNs too, such as the execution role passed to
+// lambda:CreateFunction or the encryption context of a KMS call.
+func resourceArns(e *types.CloudTrailEvent, action string) []string {
+	seen := make(map[string]bool)
+	var arns []string
+	add := func(arn string) {
+		if !seen[arn] {
+			seen[arn] = true
+			arns = append(arns, arn)
+		}
+	}
+
+	params, ok := resourceParameters[action]
+	if !ok {
+		params = resourceParameters[strings.SplitN(action, ":", 2)[0]]
+	}
+	for _, param := range params {
+		if value := types.LookupString(e.RequestParameters, param); strings.HasPrefix(value, "arn:") {
+			add(value)
+		}
+	}
+
+	// S3 identifies buckets and objects by name rather than ARN
+	if e.EventSource == "s3.amazonaws.com" {
+		if bucket := types.LookupString(e.RequestParameters, "bucketName"); bucket != "" {
+			if key := types.LookupString(e.RequestParameters, "key"); key != "" {
+				add("arn:aws:s3:::" + bucket + "/" + key)
+			} else {
+				add("arn:aws:s3:::" + bucket)
+			}
+		}
+	}
+
+	// IAM identities are referenced by name within the calling account
+	if e.EventSource == "iam.amazonaws.com" && e.RecipientAccountId != "" {
+		for param, kind := range iamNameParameters {
+			if name := types.LookupString(e.RequestParameters, param); name != "" {
+				add("arn:aws:iam::" + e.RecipientAccountId + ":" + kind + "/" + name)
+			}
+		}
+	}
+
+	sort.Strings(arns)
+	return arns
+}
+
+// iamNameParameters maps IAM request parameters holding a name to their resource type
+var iamNameParameters = map[string]string{
+	"userName":  "user",
+	"roleName":  "role",
+	"groupName": "group",
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func TestActions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		eventSource string
+		eventName   string
+		expected    []string
+		ok          bool
+	}{
+		{"Direct mapping", "iam.amazonaws.com", "CreateUser", []string{"iam:CreateUser"}, true},
+		{"Service prefix differs", "monitoring.amazonaws.com", "PutMetricAlarm", []string{"cloudwatch:PutMetricAlarm"}, true},
+		{"Action name differs", "s3.amazonaws.com", "ListBuckets", []string{"s3:ListAllMyBuckets"}, true},
+		{"One event requires two actions", "kms.amazonaws.com", "ReEncrypt", []string{"kms:ReEncryptFrom", "kms:ReEncryptTo"}, true},
+		{"Lambda API version suffix", "lambda.amazonaws.com", "UpdateFunctionCode20150331v2", []string{"lambda:UpdateFunctionCode"}, true},
+		{"API Gateway HTTP verbs", "apigateway.amazonaws.com", "GetRestApis", []string{"apigateway:GET"}, true},
+		{"No permission needed", "sts.amazonaws.com", "GetCallerIdentity", []string{}, true},
+		{"Console sign-in", "signin.amazonaws.com", "ConsoleLogin", nil, true},
+		{"Not an AWS service", "example.com", "Foo", nil, false},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := Actions(tc.eventSource, tc.eventName)
+			if ok != tc.ok || !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Actions(%q, %q) = %v, %v, want %v, %v", tc.eventSource, tc.eventName, got, ok, tc.expected, tc.ok)
+			}
+		})
+	}
+}
+
+func TestGeneratorDocument(t *testing.T) {
+	g := NewGenerator(false)
+	g.Add(&types.CloudTrailEvent{EventSource: "iam.amazonaws.com", EventName: "CreateUser"})
+	g.Add(&types.CloudTrailEvent{EventSource: "iam.amazonaws.com", EventName: "CreateUser"})
+	g.Add(&types.CloudTrailEvent{EventSource: "iam.amazonaws.com", EventName: "AttachUserPolicy"})
+	g.Add(&types.CloudTrailEvent{EventSource: "ec2.amazonaws.com", EventName: "DescribeInstances"})
+	g.Add(&types.CloudTrailEvent{EventSource: "ec2.amazonaws.com", EventName: "TerminateInstances", ErrorCode: "UnauthorizedOperation"})
+
+	data, err := json.Marshal(g.Document())
+	if err != nil {
+		t.Fatalf("failed to marshal policy: %v", err)
+	}
+
+	expected := `{"Version":"2012-10-17","Statement":[` +
+		`{"Sid":"Ec2","Effect":"Allow","Action":["ec2:DescribeInstances"],"Resource":"*"},` +
+		`{"Sid":"Iam","Effect":"Allow","Action":["iam:AttachUserPolicy","iam:CreateUser"],"Resource":"*"}]}`
+	if string(data) != expected {
+		t.Errorf("Document() = %s, want %s", data, expected)
+	}
+}
+
+func TestGeneratorResources(t *testing.T) {
+	g := NewGenerator(true)
+	g.Add(&types.CloudTrailEvent{
+		EventSource:       "s3.amazonaws.com",
+		EventName:         "PutBucketPolicy",
+		RequestParameters: map[string]interface{}{"bucketName": "assets"},
+	})
+	g.Add(&types.CloudTrailEvent{
+		EventSource:        "iam.amazonaws.com",
+		EventName:          "CreateRole",
+		RecipientAccountId: "123456789012",
+		RequestParameters:  map[string]interface{}{"roleName": "deploy"},
+	})
+	g.Add(&types.CloudTrailEvent{
+		EventSource:       "kms.amazonaws.com",
+		EventName:         "DescribeKey",
+		RequestParameters: map[string]interface{}{"keyId": "arn:aws:kms:us-east-1:123456789012:key/abcd"},
+	})
+	g.Add(&types.CloudTrailEvent{EventSource: "kms.amazonaws.com", EventName: "ListKeys"})
+
+	doc := g.Document()
+	got := make(map[string]interface{})
+	for _, statement := range doc.Statement {
+		got[statement.Sid] = statement.Resource
+	}
+
+	expected := map[string]interface{}{
+		"Iam":       []string{"arn:aws:iam::123456789012:role/deploy"},
+		"KmsGroup1": "*",
+		"KmsGroup2": []string{"arn:aws:kms:us-east-1:123456789012:key/abcd"},
+		"S3":        []string{"arn:aws:s3:::assets"},
+	}
+	if len(doc.Statement) != 4 {
+		t.Fatalf("Document() returned %d statements, want 4: %+v", len(doc.Statement), doc.Statement)
+	}
+	for sid, resource := range expected {
+		if !reflect.DeepEqual(got[sid], resource) {
+			t.Errorf("statement %s resource = %v, want %v", sid, got[sid], resource)
+		}
+	}
+}
+
+func TestResourceArns(t *testing.T) {
+	const keyArn = "arn:aws:kms:us-east-1:123456789012:key/abcd"
+	const otherKeyArn = "arn:aws:kms:us-east-1:123456789012:key/ef01"
+
+	testCases := []struct {
+		name     string
+		event    *types.CloudTrailEvent
+		action   string
+		expected []string
+	}{
+		{
+			name: "Passed role is not the resource",
+			event: &types.CloudTrailEvent{
+				EventSource: "lambda.amazonaws.com",
+				RequestParameters: map[string]interface{}{
+					"functionName": "deploy",
+					"role":         "arn:aws:iam::123456789012:role/deploy-execution",
+				},
+			},
+			action:   "lambda:CreateFunction",
+			expected: nil,
+		},
+		{
+			name: "Function ARN",
+			event: &types.CloudTrailEvent{
+				EventSource:       "lambda.amazonaws.com",
+				RequestParameters: map[string]interface{}{"functionName": "arn:aws:lambda:us-east-1:123456789012:function:deploy"},
+			},
+			action:   "lambda:InvokeFunction",
+			expected: []string{"arn:aws:lambda:us-east-1:123456789012:function:deploy"},
+		},
+		{
+			name: "Encryption context is not the resource",
+			event: &types.CloudTrailEvent{
+				EventSource: "kms.amazonaws.com",
+				RequestParameters: map[string]interface{}{
+					"keyId":             keyArn,
+					"encryptionContext": map[string]interface{}{"aws:s3:arn": "arn:aws:s3:::assets/report.csv"},
+				},
+			},
+			action:   "kms:Decrypt",
+			expected: []string{keyArn},
+		},
+		{
+			name: "Per-action parameter",
+			event: &types.CloudTrailEvent{
+				EventSource:       "kms.amazonaws.com",
+				RequestParameters: map[string]interface{}{"sourceKeyId": keyArn, "destinationKeyId": otherKeyArn},
+			},
+			action:   "kms:ReEncryptTo",
+			expected: []string{otherKeyArn},
+		},
+		{
+			name: "Unmapped service",
+			event: &types.CloudTrailEvent{
+				EventSource:       "ec2.amazonaws.com",
+				RequestParameters: map[string]interface{}{"iamInstanceProfile": map[string]interface{}{"arn": "arn:aws:iam::123456789012:instance-profile/web"}},
+			},
+			action:   "ec2:RunInstances",
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			got := resourceArns(tc.event, tc.action)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("resourceArns(%q) = %v, want %v", tc.action, got, tc.expected)
+			}
+		})
+	}
+}
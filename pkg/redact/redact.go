@@ -0,0 +1,240 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultRequestParameters are the requestParameters paths redacted by default
+var DefaultRequestParameters = []string{
+	"*password",
+	"*passphrase",
+	"secretString",
+	"secretBinary",
+	"privateKey",
+	"clientSecret",
+	"sessionToken",
+}
+
+// Rules selects what is redacted. RequestParameters are dotted paths below
+// requestParameters where each segment may use "*" wildcards; a path without
+// dots matches keys of that name at any depth.
+type Rules struct {
+	AccountIds        bool
+	AccessKeyIds      bool
+	IPAddresses       bool
+	RequestParameters []string
+}
+
+// DefaultRules redacts everything the redactor knows about
+func DefaultRules() Rules {
+	return Rules{
+		AccountIds:        true,
+		AccessKeyIds:      true,
+		IPAddresses:       true,
+		RequestParameters: DefaultRequestParameters,
+	}
+}
+
+var (
+	accountIdPattern   = regexp.MustCompile(`\b\d{12}\b`)
+	accessKeyIdPattern = regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`)
+	ipv4Pattern        = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern        = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]`)
+
+	// uuidPrefix matches the start of a UUID whose last group would look like an account ID
+	uuidPrefix = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-$`)
+)
+
+// Redactor replaces sensitive values with pseudonyms. The same value always
+// gets the same pseudonym from a Redactor, so redacted output stays correlatable.
+// A Redactor is safe for concurrent use; a nil Redactor leaves values untouched.
+type Redactor struct {
+	rules      Rules
+	mu         sync.Mutex
+	pseudonyms map[string]map[string]string
+}
+
+// New creates a redactor applying the given rules
+func New(rules Rules) *Redactor {
+	return &Redactor{rules: rules, pseudonyms: make(map[string]map[string]string)}
+}
+
+// pseudonym returns the stable replacement of a value within a category
+func (r *Redactor) pseudonym(category, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values, ok := r.pseudonyms[category]
+	if !ok {
+		values = make(map[string]string)
+		r.pseudonyms[category] = values
+	}
+	if p, ok := values[value]; ok {
+		return p
+	}
+	p := fmt.Sprintf("%s-%d", category, len(values)+1)
+	values[value] = p
+	return p
+}
+
+// replaceMatches replaces the matches of a pattern for which replace returns true
+func replaceMatches(s string, re *regexp.Regexp, replace func(s string, start, end int) (string, bool)) string {
+	matches := re.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		replacement, ok := replace(s, m[0], m[1])
+		if !ok {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(replacement)
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Text redacts the account IDs, access key IDs and IP addresses found in free text
+func (r *Redactor) Text(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+
+	if r.rules.AccessKeyIds {
+		s = replaceMatches(s, accessKeyIdPattern, func(s string, start, end int) (string, bool) {
+			// The prefix tells long-term (AKIA) and temporary (ASIA) keys apart
+			return s[start:start+4] + "-" + r.pseudonym("key", s[start:end]), true
+		})
+	}
+	if r.rules.AccountIds {
+		s = replaceMatches(s, accountIdPattern, func(s string, start, end int) (string, bool) {
+			if uuidPrefix.MatchString(s[:start]) {
+				return "", false
+			}
+			return r.pseudonym("account", s[start:end]), true
+		})
+	}
+	if r.rules.IPAddresses {
+		ip := func(s string, start, end int) (string, bool) {
+			if net.ParseIP(s[start:end]) == nil {
+				return "", false
+			}
+			return r.pseudonym("ip", s[start:end]), true
+		}
+		s = replaceMatches(s, ipv4Pattern, ip)
+		s = replaceMatches(s, ipv6Pattern, func(s string, start, end int) (string, bool) {
+			// Skip the tail of words such as "iam::aws" in ARNs
+			if strings.Count(s[start:end], ":") < 2 || isWordByte(s, start-1) || isWordByte(s, end) {
+				return "", false
+			}
+			return ip(s, start, end)
+		})
+	}
+	return s
+}
+
+// isWordByte reports whether the byte at n is a letter or digit
+func isWordByte(s string, n int) bool {
+	if n < 0 || n >= len(s) {
+		return false
+	}
+	c := s[n]
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// matchesRequestParameter reports whether a path below requestParameters is redacted
+func (r *Redactor) matchesRequestParameter(keys []string) bool {
+	for _, rule := range r.rules.RequestParameters {
+		segments := strings.Split(strings.ToLower(rule), ".")
+		candidate := keys
+		if len(segments) == 1 {
+			candidate = keys[len(keys)-1:]
+		}
+		if len(candidate) != len(segments) {
+			continue
+		}
+		matched := true
+		for n, segment := range segments {
+			if ok, _ := path.Match(segment, strings.ToLower(candidate[n])); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue walks a decoded JSON value. keys is the path below
+// requestParameters, or nil outside of it.
+func (r *Redactor) redactValue(v interface{}, keys []string, inRequestParameters bool) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		// Sorted keys number the pseudonyms deterministically
+		names := make([]string, 0, len(node))
+		for key := range node {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			child := node[key]
+			childKeys := append(append([]string{}, keys...), key)
+			if inRequestParameters && r.matchesRequestParameter(childKeys) {
+				encoded, _ := json.Marshal(child)
+				node[key] = r.pseudonym("secret", string(encoded))
+				continue
+			}
+			if !inRequestParameters && keys == nil && key == "requestParameters" {
+				node[key] = r.redactValue(child, []string{}, true)
+				continue
+			}
+			node[key] = r.redactValue(child, childKeys, inRequestParameters)
+		}
+		return node
+	case []interface{}:
+		for n, child := range node {
+			node[n] = r.redactValue(child, keys, inRequestParameters)
+		}
+		return node
+	case string:
+		return r.Text(node)
+	}
+	return v
+}
+
+// JSON redacts a CloudTrail record: every string value is redacted as Text
+// and the selected requestParameters paths are replaced entirely
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	if r == nil {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r.redactValue(v, nil, false)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
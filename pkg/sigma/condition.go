@@ -0,0 +1,204 @@
+package sigma
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// node is one element of a compiled detection expression
+type node interface {
+	eval(event map[string]interface{}) bool
+}
+
+type andNode []node
+
+func (n andNode) eval(event map[string]interface{}) bool {
+	for _, child := range n {
+		if !child.eval(event) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []node
+
+func (n orNode) eval(event map[string]interface{}) bool {
+	for _, child := range n {
+		if child.eval(event) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct {
+	child node
+}
+
+func (n notNode) eval(event map[string]interface{}) bool {
+	return !n.child.eval(event)
+}
+
+// conditionParser is a recursive descent parser for Sigma condition expressions:
+//
+//	expr   := term { "or" term }
+//	term   := factor { "and" factor }
+//	factor := "not" factor | "(" expr ")" | quantifier "of" pattern | identifier
+type conditionParser struct {
+	tokens   []string
+	pos      int
+	searches map[string]node
+}
+
+// tokenizeCondition splits a condition into words and parentheses
+func tokenizeCondition(condition string) []string {
+	condition = strings.ReplaceAll(condition, "(", " ( ")
+	condition = strings.ReplaceAll(condition, ")", " ) ")
+	return strings.Fields(condition)
+}
+
+// parseCondition compiles a condition string against the named searches of a rule
+func parseCondition(condition string, searches map[string]node) (node, error) {
+	if strings.Contains(condition, "|") {
+		return nil, fmt.Errorf("aggregation expressions are not supported: %q", condition)
+	}
+
+	p := &conditionParser{tokens: tokenizeCondition(condition), searches: searches}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in condition %q", p.tokens[p.pos], condition)
+	}
+	return n, nil
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *conditionParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *conditionParser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	alternatives := orNode{left}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, right)
+	}
+	if len(alternatives) == 1 {
+		return left, nil
+	}
+	return alternatives, nil
+}
+
+func (p *conditionParser) parseTerm() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	all := andNode{left}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, right)
+	}
+	if len(all) == 1 {
+		return left, nil
+	}
+	return all, nil
+}
+
+func (p *conditionParser) parseFactor() (node, error) {
+	token := p.next()
+	switch strings.ToLower(token) {
+	case "":
+		return nil, fmt.Errorf("unexpected end of condition")
+	case "not":
+		child, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	case "(":
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return inner, nil
+	case "1", "any", "all":
+		if !strings.EqualFold(p.peek(), "of") {
+			break
+		}
+		p.next()
+		return p.parseQuantifier(strings.ToLower(token), p.next())
+	}
+
+	search, ok := p.searches[token]
+	if !ok {
+		return nil, fmt.Errorf("condition references unknown search %q", token)
+	}
+	return search, nil
+}
+
+// parseQuantifier resolves "1 of pattern" / "all of pattern" / "... of them"
+func (p *conditionParser) parseQuantifier(quantifier, pattern string) (node, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("missing search pattern after %q of", quantifier)
+	}
+
+	var names []string
+	for name := range p.searches {
+		if pattern == "them" {
+			// Searches prefixed with an underscore are helpers excluded from "them"
+			if !strings.HasPrefix(name, "_") {
+				names = append(names, name)
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("pattern %q matches no search", pattern)
+	}
+	sort.Strings(names)
+
+	children := make([]node, 0, len(names))
+	for _, name := range names {
+		children = append(children, p.searches[name])
+	}
+	if quantifier == "all" {
+		return andNode(children), nil
+	}
+	return orNode(children), nil
+}
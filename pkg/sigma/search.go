@@ -0,0 +1,314 @@
+package sigma
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// compileSearch compiles a named search of the detection section. A map is a
+// conjunction of field conditions, a list of maps is a disjunction of those and
+// a list of plain values is a keyword search.
+func compileSearch(definition interface{}) (node, error) {
+	switch d := definition.(type) {
+	case map[string]interface{}:
+		return compileFieldMap(d)
+	case []interface{}:
+		var alternatives orNode
+		for _, item := range d {
+			if m, ok := item.(map[string]interface{}); ok {
+				n, err := compileFieldMap(m)
+				if err != nil {
+					return nil, err
+				}
+				alternatives = append(alternatives, n)
+				continue
+			}
+			matcher, err := compileValue(item, []string{"contains"})
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, keywordNode{match: matcher})
+		}
+		return alternatives, nil
+	case string:
+		matcher, err := compileValue(d, []string{"contains"})
+		if err != nil {
+			return nil, err
+		}
+		return keywordNode{match: matcher}, nil
+	}
+	return nil, fmt.Errorf("unsupported search definition of type %T", definition)
+}
+
+// compileFieldMap compiles "field|modifier: value(s)" pairs that must all match
+func compileFieldMap(m map[string]interface{}) (node, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := andNode{}
+	for _, key := range keys {
+		n, err := compileField(key, m[key])
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, n)
+	}
+	return all, nil
+}
+
+// fieldNode matches the values of a single (possibly nested) record field
+type fieldNode struct {
+	field    string
+	matchers []valueMatcher
+	all      bool
+}
+
+func (n fieldNode) eval(event map[string]interface{}) bool {
+	values, found := resolveField(event, n.field)
+	if n.all {
+		for _, match := range n.matchers {
+			if !match(values, found) {
+				return false
+			}
+		}
+		return len(n.matchers) > 0
+	}
+	for _, match := range n.matchers {
+		if match(values, found) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordNode matches a value anywhere in the record
+type keywordNode struct {
+	match valueMatcher
+}
+
+func (n keywordNode) eval(event map[string]interface{}) bool {
+	return n.match(collectLeaves(event, nil), true)
+}
+
+// valueMatcher reports whether any of the resolved field values satisfy a pattern
+type valueMatcher func(values []interface{}, found bool) bool
+
+var supportedModifiers = map[string]bool{
+	"contains":   true,
+	"startswith": true,
+	"endswith":   true,
+	"all":        true,
+	"re":         true,
+	"cidr":       true,
+	"exists":     true,
+}
+
+// compileField parses "field|mod1|mod2" and its expected value(s)
+func compileField(key string, value interface{}) (node, error) {
+	parts := strings.Split(key, "|")
+	field, modifiers := parts[0], parts[1:]
+
+	all := false
+	for _, modifier := range modifiers {
+		if !supportedModifiers[modifier] {
+			return nil, fmt.Errorf("unsupported modifier %q on field %q", modifier, field)
+		}
+		if modifier == "all" {
+			all = true
+		}
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	n := fieldNode{field: field, all: all}
+	for _, v := range values {
+		matcher, err := compileValue(v, modifiers)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		n.matchers = append(n.matchers, matcher)
+	}
+	return n, nil
+}
+
+// compileValue builds the matcher for one expected value under the given modifiers
+func compileValue(value interface{}, modifiers []string) (valueMatcher, error) {
+	has := func(name string) bool {
+		for _, modifier := range modifiers {
+			if modifier == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	if has("exists") {
+		want, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("exists modifier expects a boolean")
+		}
+		return func(values []interface{}, found bool) bool { return found == want }, nil
+	}
+
+	// A null value matches absent or null fields
+	if value == nil {
+		return func(values []interface{}, found bool) bool {
+			if !found {
+				return true
+			}
+			for _, v := range values {
+				if v == nil {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	expected := fmt.Sprint(value)
+
+	if has("cidr") {
+		_, network, err := net.ParseCIDR(expected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", expected)
+		}
+		return anyValue(func(s string) bool {
+			ip := net.ParseIP(s)
+			return ip != nil && network.Contains(ip)
+		}), nil
+	}
+
+	if has("re") {
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", expected, err)
+		}
+		return anyValue(re.MatchString), nil
+	}
+
+	// Plain values are case-insensitive wildcard patterns
+	pattern := globToRegexp(expected)
+	switch {
+	case has("contains"):
+		pattern = ".*" + pattern + ".*"
+	case has("startswith"):
+		pattern = pattern + ".*"
+	case has("endswith"):
+		pattern = ".*" + pattern
+	}
+	re, err := regexp.Compile("(?is)^" + pattern + "$")
+	if err != nil {
+		return nil, err
+	}
+	return anyValue(re.MatchString), nil
+}
+
+// anyValue adapts a string predicate to match any resolved value
+func anyValue(predicate func(string) bool) valueMatcher {
+	return func(values []interface{}, found bool) bool {
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if predicate(fmt.Sprint(v)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// globToRegexp converts a Sigma wildcard pattern into a regular expression body.
+// "*" and "?" are wildcards unless escaped with a backslash.
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern) && strings.ContainsRune(`*?\`, rune(pattern[i+1])):
+			b.WriteString(regexp.QuoteMeta(string(pattern[i+1])))
+			i++
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// resolveField returns the values at a dotted field path. Lists along the way
+// are flattened so any element can satisfy a condition.
+func resolveField(event map[string]interface{}, field string) ([]interface{}, bool) {
+	if v, ok := event[field]; ok {
+		return flatten(v), true
+	}
+
+	current := []interface{}{event}
+	for _, key := range strings.Split(field, ".") {
+		var nextValues []interface{}
+		for _, v := range current {
+			for _, item := range flatten(v) {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if child, ok := m[key]; ok {
+					nextValues = append(nextValues, child)
+				}
+			}
+		}
+		if len(nextValues) == 0 {
+			return nil, false
+		}
+		current = nextValues
+	}
+
+	var values []interface{}
+	for _, v := range current {
+		values = append(values, flatten(v)...)
+	}
+	return values, true
+}
+
+// flatten expands lists into their elements
+func flatten(v interface{}) []interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return []interface{}{v}
+	}
+	var values []interface{}
+	for _, item := range list {
+		values = append(values, flatten(item)...)
+	}
+	return values
+}
+
+// collectLeaves gathers every scalar value of a record for keyword searches
+func collectLeaves(v interface{}, leaves []interface{}) []interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for _, child := range node {
+			leaves = collectLeaves(child, leaves)
+		}
+	case []interface{}:
+		for _, child := range node {
+			leaves = collectLeaves(child, leaves)
+		}
+	case nil:
+	default:
+		leaves = append(leaves, node)
+	}
+	return leaves
+}
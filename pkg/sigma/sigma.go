@@ -0,0 +1,257 @@
+package sigma
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// References:
+// - https://sigmahq.io/docs/basics/rules.html
+// - https://github.com/SigmaHQ/sigma-specification
+
+// Logsource identifies which logs a rule applies to
+type Logsource struct {
+	Product  string `yaml:"product"`
+	Service  string `yaml:"service"`
+	Category string `yaml:"category"`
+}
+
+// IsCloudTrail reports whether the logsource is aws/cloudtrail
+func (l Logsource) IsCloudTrail() bool {
+	return strings.EqualFold(l.Product, "aws") && strings.EqualFold(l.Service, "cloudtrail")
+}
+
+// ruleDocument mirrors the YAML layout of a Sigma rule
+type ruleDocument struct {
+	Title       string                 `yaml:"title"`
+	Id          string                 `yaml:"id"`
+	Status      string                 `yaml:"status"`
+	Description string                 `yaml:"description"`
+	Level       string                 `yaml:"level"`
+	Tags        []string               `yaml:"tags"`
+	Logsource   Logsource              `yaml:"logsource"`
+	Detection   map[string]interface{} `yaml:"detection"`
+}
+
+// Rule is a parsed Sigma rule ready to be evaluated against CloudTrail records
+type Rule struct {
+	Title       string
+	Id          string
+	Status      string
+	Description string
+	Level       string
+	Tags        []string
+	Logsource   Logsource
+	Path        string
+
+	condition node
+}
+
+// IsCloudTrail reports whether the rule targets the aws/cloudtrail logsource
+func (r *Rule) IsCloudTrail() bool {
+	return r.Logsource.IsCloudTrail()
+}
+
+// Matches evaluates the rule detection against a decoded CloudTrail record
+func (r *Rule) Matches(event map[string]interface{}) bool {
+	return r.condition.eval(event)
+}
+
+// decodeRules decodes every YAML document in data without compiling it
+func decodeRules(data []byte) ([]ruleDocument, error) {
+	var docs []ruleDocument
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc ruleDocument
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule YAML: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// ParseRules parses every YAML document in data as a Sigma rule
+func ParseRules(data []byte) ([]*Rule, error) {
+	docs, err := decodeRules(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+	for _, doc := range docs {
+		rule, err := compileRule(doc)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// compileRule turns a rule document into an evaluable rule
+func compileRule(doc ruleDocument) (*Rule, error) {
+	if doc.Title == "" {
+		return nil, fmt.Errorf("rule is missing a title")
+	}
+	if len(doc.Detection) == 0 {
+		return nil, fmt.Errorf("rule %q has no detection section", doc.Title)
+	}
+
+	conditions, err := conditionStrings(doc.Detection["condition"])
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", doc.Title, err)
+	}
+
+	searches := make(map[string]node)
+	for name, definition := range doc.Detection {
+		if name == "condition" || name == "timeframe" {
+			continue
+		}
+		search, err := compileSearch(definition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q, search %q: %w", doc.Title, name, err)
+		}
+		searches[name] = search
+	}
+
+	// Multiple conditions are alternatives of each other
+	var alternatives orNode
+	for _, condition := range conditions {
+		parsed, err := parseCondition(condition, searches)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", doc.Title, err)
+		}
+		alternatives = append(alternatives, parsed)
+	}
+
+	return &Rule{
+		Title:       doc.Title,
+		Id:          doc.Id,
+		Status:      doc.Status,
+		Description: doc.Description,
+		Level:       doc.Level,
+		Tags:        doc.Tags,
+		Logsource:   doc.Logsource,
+		condition:   alternatives,
+	}, nil
+}
+
+// conditionStrings normalizes the condition field, which may be a string or a list
+func conditionStrings(v interface{}) ([]string, error) {
+	switch c := v.(type) {
+	case string:
+		return []string{c}, nil
+	case []interface{}:
+		var conditions []string
+		for _, item := range c {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("condition list must contain strings")
+			}
+			conditions = append(conditions, s)
+		}
+		if len(conditions) > 0 {
+			return conditions, nil
+		}
+	}
+	return nil, fmt.Errorf("missing detection condition")
+}
+
+// Skipped is a rule LoadRules left out and the reason why
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// LoadRules reads Sigma rules from files and directories. Only the rules with
+// the aws/cloudtrail logsource are compiled; the others, and the rules using
+// features the evaluator does not support, are returned as skipped.
+func LoadRules(paths []string) ([]*Rule, []Skipped, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := strings.ToLower(filepath.Ext(p))
+			if !d.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read rules from %s: %w", path, err)
+		}
+	}
+	sort.Strings(files)
+
+	var rules []*Rule
+	var skipped []Skipped
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read rule file %s: %w", file, err)
+		}
+		docs, err := decodeRules(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, doc := range docs {
+			// Rules for other logsources may use modifiers or aggregations
+			// that are not supported, so they are not compiled at all
+			if !doc.Logsource.IsCloudTrail() {
+				skipped = append(skipped, Skipped{Path: file, Reason: "logsource is not aws/cloudtrail"})
+				continue
+			}
+			rule, err := compileRule(doc)
+			if err != nil {
+				skipped = append(skipped, Skipped{Path: file, Reason: err.Error()})
+				continue
+			}
+			rule.Path = file
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, skipped, nil
+}
+
+// Match groups the records that triggered one rule
+type Match struct {
+	Rule   *Rule
+	Events []map[string]interface{}
+}
+
+// Evaluate runs every rule over the records and returns the rules that matched
+func Evaluate(rules []*Rule, events []map[string]interface{}) []Match {
+	var matches []Match
+	for _, rule := range rules {
+		match := Match{Rule: rule}
+		for _, event := range events {
+			if rule.Matches(event) {
+				match.Events = append(match.Events, event)
+			}
+		}
+		if len(match.Events) > 0 {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
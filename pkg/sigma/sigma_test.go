@@ -0,0 +1,175 @@
+package sigma
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func loadSampleEvents(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "events", "sample.json"))
+	if err != nil {
+		t.Fatalf("failed to read sample events: %v", err)
+	}
+	var logFile struct {
+		Records []map[string]interface{} `json:"Records"`
+	}
+	if err := json.Unmarshal(data, &logFile); err != nil {
+		t.Fatalf("failed to parse sample events: %v", err)
+	}
+	return logFile.Records
+}
+
+func TestLoadRulesSkipsOtherLogsources(t *testing.T) {
+	rules, skipped, err := LoadRules([]string{filepath.Join("testdata", "rules")})
+	if err != nil {
+		t.Fatalf("LoadRules() failed: %v", err)
+	}
+	if len(rules) != 7 {
+		t.Errorf("LoadRules() returned %d rules, want 7", len(rules))
+	}
+
+	reasons := make(map[string]string)
+	for _, rule := range skipped {
+		reasons[filepath.Base(rule.Path)] = rule.Reason
+	}
+	expected := map[string]string{
+		// Its unsupported modifiers are never compiled, so they are not reported
+		"windows_encoded_powershell.yml": "logsource is not aws/cloudtrail",
+		"windows_process_creation.yml":   "logsource is not aws/cloudtrail",
+		"aws_console_login_burst.yml":    `rule "AWS Console Login Burst": aggregation expressions are not supported: "selection | count() by sourceIPAddress > 10"`,
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("LoadRules() skipped %v, want %v", reasons, expected)
+	}
+}
+
+func TestEvaluateCorpus(t *testing.T) {
+	rules, _, err := LoadRules([]string{filepath.Join("testdata", "rules")})
+	if err != nil {
+		t.Fatalf("LoadRules() failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"AWS CloudTrail Important Change":                        {"11111111-1111-4111-8111-111111111111"},
+		"AWS Root Credentials":                                   {"22222222-2222-4222-8222-222222222222"},
+		"AWS Console Login Without MFA":                          {"22222222-2222-4222-8222-222222222222"},
+		"AWS S3 Bucket Policy Or ACL Change":                     {"44444444-4444-4444-8444-444444444444"},
+		"AWS STS GetSessionToken From Outside Corporate Network": {"77777777-7777-4777-8777-777777777777"},
+		"AWS Suspicious User Agent Keyword":                      {"11111111-1111-4111-8111-111111111111"},
+	}
+
+	got := make(map[string][]string)
+	for _, match := range Evaluate(rules, loadSampleEvents(t)) {
+		for _, event := range match.Events {
+			got[match.Rule.Title] = append(got[match.Rule.Title], event["eventID"].(string))
+		}
+	}
+	for title := range got {
+		sort.Strings(got[title])
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Evaluate() matches = %v, want %v", got, expected)
+	}
+}
+
+func TestRuleModifiers(t *testing.T) {
+	event := map[string]interface{}{
+		"eventName":       "CreateAccessKey",
+		"sourceIPAddress": "2001:db8::1",
+		"userAgent":       "Boto3/1.34.0 md/Botocore#1.34.0",
+		"requestParameters": map[string]interface{}{
+			"userName": "ci-bot",
+		},
+		"resources": []interface{}{
+			map[string]interface{}{"ARN": "arn:aws:iam::123456789012:user/ci-bot"},
+			map[string]interface{}{"ARN": "arn:aws:iam::123456789012:user/other"},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		detection string
+		expected  bool
+	}{
+		{"Case-insensitive equality", "eventName: createaccesskey", true},
+		{"Wildcard", "eventName: Create*Key", true},
+		{"Escaped wildcard is literal", `eventName: 'Create\*Key'`, false},
+		{"Contains all", "userAgent|contains|all: [Boto3, Botocore]", true},
+		{"Contains all missing one", "userAgent|contains|all: [Boto3, aws-cli]", false},
+		{"Endswith", "requestParameters.userName|endswith: -bot", true},
+		{"Regular expression", `userAgent|re: '^Boto3/1\.3[0-9]'`, true},
+		{"IPv6 CIDR", "sourceIPAddress|cidr: '2001:db8::/32'", true},
+		{"Null matches absent field", "errorCode: null", true},
+		{"Exists false", "errorCode|exists: false", true},
+		{"List element in nested field", "resources.ARN|endswith: /other", true},
+		{"List of values is OR", "eventName: [DeleteUser, CreateAccessKey]", true},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			doc := "title: test\nlogsource: {product: aws, service: cloudtrail}\ndetection:\n  selection:\n    " +
+				tc.detection + "\n  condition: selection\n"
+			rules, err := ParseRules([]byte(doc))
+			if err != nil {
+				t.Fatalf("ParseRules() failed: %v", err)
+			}
+			if got := rules[0].Matches(event); got != tc.expected {
+				t.Errorf("Matches() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		condition string
+	}{
+		{"Unknown search", "selection and missing"},
+		{"Unbalanced parenthesis", "(selection or filter"},
+		{"Aggregation", "selection | count() by userIdentity.arn > 10"},
+		{"Pattern without match", "1 of nothing_*"},
+		{"Trailing token", "selection filter"},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			doc := "title: test\nlogsource: {product: aws, service: cloudtrail}\ndetection:\n" +
+				"  selection: {eventName: A}\n  filter: {eventName: B}\n  condition: '" + tc.condition + "'\n"
+			if _, err := ParseRules([]byte(doc)); err == nil {
+				t.Errorf("expected error for condition %q", tc.condition)
+			}
+		})
+	}
+}
+
+func TestConditionPrecedence(t *testing.T) {
+	doc := `title: precedence
+logsource: {product: aws, service: cloudtrail}
+detection:
+  a: {eventName: A}
+  b: {eventName: B}
+  c: {eventSource: c.amazonaws.com}
+  condition: a or b and c
+`
+	rules, err := ParseRules([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseRules() failed: %v", err)
+	}
+
+	// "and" binds tighter than "or": a or (b and c)
+	if !rules[0].Matches(map[string]interface{}{"eventName": "A"}) {
+		t.Error("expected event A to match")
+	}
+	if rules[0].Matches(map[string]interface{}{"eventName": "B"}) {
+		t.Error("expected event B without source c to not match")
+	}
+}
@@ -0,0 +1,268 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// view is one result set of the navigation history
+type view struct {
+	title string
+	rows  []Row
+}
+
+// browser wires the model to the terminal widgets
+type browser struct {
+	app     *tview.Application
+	pages   *tview.Pages
+	table   *tview.Table
+	detail  *tview.TextView
+	search  *tview.InputField
+	status  *tview.TextView
+	model   *Model
+	pivots  []Pivot
+	history []view
+	visible []Row
+
+	// querying is set while a pivot runs. It is only accessed from the UI
+	// goroutine, pivots being answered through QueueUpdateDraw.
+	querying bool
+}
+
+// Run displays the events until the user quits
+func Run(title string, header []string, rows []Row, pivots []Pivot) error {
+	b := &browser{
+		app:     tview.NewApplication(),
+		pages:   tview.NewPages(),
+		table:   tview.NewTable(),
+		detail:  tview.NewTextView(),
+		search:  tview.NewInputField(),
+		status:  tview.NewTextView(),
+		model:   NewModel(header, rows),
+		pivots:  pivots,
+		history: []view{{title: title, rows: rows}},
+	}
+
+	b.table.SetSelectable(true, false).SetFixed(1, 0).SetBorder(true)
+	b.table.SetSelectionChangedFunc(func(row, column int) { b.showDetail(row) })
+	b.table.SetInputCapture(b.handleKey)
+
+	b.detail.SetDynamicColors(false).SetScrollable(true).SetWrap(false)
+	b.detail.SetBorder(true).SetTitle(" Event ")
+
+	b.search.SetLabel("/").SetFieldBackgroundColor(tcell.ColorDefault)
+	b.search.SetChangedFunc(func(text string) {
+		b.model.SetSearch(text)
+		b.refresh()
+	})
+	b.search.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			b.search.SetText("")
+		}
+		b.app.SetFocus(b.table)
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(b.table, 0, 3, true).
+			AddItem(b.detail, 0, 2, false), 0, 1, true).
+		AddItem(b.search, 1, 0, false).
+		AddItem(b.status, 1, 0, false)
+
+	b.pages.AddPage("main", layout, true, true)
+	b.refresh()
+	b.setStatus("")
+
+	return b.app.SetRoot(b.pages, true).SetFocus(b.table).Run()
+}
+
+// refresh redraws the list from the model, keeping the selection in range
+func (b *browser) refresh() {
+	current := b.history[len(b.history)-1]
+	b.visible = b.model.Visible()
+	columns := b.model.VisibleColumns()
+	sortColumn, sortDesc := b.model.SortState()
+
+	b.table.Clear()
+	for idx, column := range columns {
+		name := b.model.Header[column]
+		if column == sortColumn && sortDesc {
+			name += " ▼"
+		} else if column == sortColumn {
+			name += " ▲"
+		}
+		b.table.SetCell(0, idx, tview.NewTableCell(name).
+			SetSelectable(false).
+			SetTextColor(tcell.ColorYellow).
+			SetAttributes(tcell.AttrBold))
+	}
+	for r, row := range b.visible {
+		for idx, column := range columns {
+			value := strings.ReplaceAll(cell(row, column), "\n", ", ")
+			b.table.SetCell(r+1, idx, tview.NewTableCell(tview.Escape(value)).SetMaxWidth(48))
+		}
+	}
+
+	b.table.SetTitle(fmt.Sprintf(" %s (%d/%d) ", current.title, len(b.visible), len(current.rows)))
+	if selected, _ := b.table.GetSelection(); selected > len(b.visible) || selected < 1 {
+		b.table.Select(1, 0)
+	}
+	selected, _ := b.table.GetSelection()
+	b.showDetail(selected)
+}
+
+// showDetail displays the full event of a table row
+func (b *browser) showDetail(row int) {
+	if row < 1 || row > len(b.visible) {
+		b.detail.SetText("")
+		return
+	}
+	b.detail.SetText(b.visible[row-1].Detail).ScrollToBeginning()
+}
+
+// setStatus shows a message, or the available hotkeys
+func (b *browser) setStatus(message string) {
+	if message == "" {
+		keys := []string{"/ search", "s sort", "c columns", "tab detail"}
+		for _, pivot := range b.pivots {
+			keys = append(keys, fmt.Sprintf("%c %s", pivot.Key, pivot.Label))
+		}
+		keys = append(keys, "b back", "q quit")
+		message = strings.Join(keys, " | ")
+	}
+	b.status.SetText(message)
+}
+
+// handleKey dispatches the hotkeys of the list
+func (b *browser) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyTab:
+		b.app.SetFocus(b.detail)
+		b.detail.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyEscape {
+				b.app.SetFocus(b.table)
+				return nil
+			}
+			return event
+		})
+		return nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		b.back()
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+
+	switch event.Rune() {
+	case 'q':
+		b.app.Stop()
+	case '/':
+		b.app.SetFocus(b.search)
+	case 's':
+		b.pickColumn(" Sort by ", func(column int) string {
+			return b.model.Header[column]
+		}, true, b.model.SortBy)
+	case 'c':
+		b.pickColumn(" Columns ", func(column int) string {
+			mark := "[x] "
+			if b.model.IsHidden(column) {
+				mark = "[ ] "
+			}
+			return mark + b.model.Header[column]
+		}, false, b.model.ToggleColumn)
+	case 'b':
+		b.back()
+	default:
+		for _, pivot := range b.pivots {
+			if pivot.Key == event.Rune() {
+				b.runPivot(pivot)
+				return nil
+			}
+		}
+		return event
+	}
+	return nil
+}
+
+// pickColumn shows a column list and applies the action to the chosen column.
+// The list closes after one choice when closeOnSelect is set.
+func (b *browser) pickColumn(title string, label func(column int) string, closeOnSelect bool, action func(column int)) {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(title)
+
+	closeList := func() {
+		b.pages.RemovePage("picker")
+		b.app.SetFocus(b.table)
+	}
+	for column := range b.model.Header {
+		column := column
+		list.AddItem(tview.Escape(label(column)), "", 0, func() {
+			action(column)
+			b.refresh()
+			if closeOnSelect {
+				closeList()
+				return
+			}
+			list.SetItemText(column, tview.Escape(label(column)), "")
+		})
+	}
+	list.SetDoneFunc(closeList)
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, len(b.model.Header)+2, 0, true).
+			AddItem(nil, 0, 1, false), 40, 0, true).
+		AddItem(nil, 0, 1, false)
+	b.pages.AddPage("picker", modal, true, true)
+	b.app.SetFocus(list)
+}
+
+// runPivot re-queries in the background and pushes the result on the history.
+// Pivots are run one at a time: keys pressed while a query is in flight are
+// ignored, so results reach the history in order and the enrichment caches are
+// never written concurrently.
+func (b *browser) runPivot(pivot Pivot) {
+	if b.querying {
+		return
+	}
+	selected, _ := b.table.GetSelection()
+	if selected < 1 || selected > len(b.visible) {
+		return
+	}
+	row := b.visible[selected-1]
+
+	b.querying = true
+	b.setStatus(fmt.Sprintf("Querying %s...", pivot.Label))
+	go func() {
+		title, rows, err := pivot.Query(row)
+		b.app.QueueUpdateDraw(func() {
+			b.querying = false
+			if err != nil {
+				b.setStatus(err.Error())
+				return
+			}
+			b.history = append(b.history, view{title: title, rows: rows})
+			b.model.SetRows(rows)
+			b.table.Select(1, 0)
+			b.refresh()
+			b.setStatus("")
+		})
+	}()
+}
+
+// back returns to the previous result set, once any running pivot is done
+func (b *browser) back() {
+	if b.querying || len(b.history) < 2 {
+		return
+	}
+	b.history = b.history[:len(b.history)-1]
+	b.model.SetRows(b.history[len(b.history)-1].rows)
+	b.table.Select(1, 0)
+	b.refresh()
+}
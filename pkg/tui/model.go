@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// Row is one event of the list
+type Row struct {
+	Cells  []string
+	Detail string
+	Event  *types.Event
+}
+
+// Pivot re-queries events related to the selected row when its key is pressed
+type Pivot struct {
+	Key   rune
+	Label string
+	Query func(row Row) (title string, rows []Row, err error)
+}
+
+// Model holds the list state independently of the terminal widgets
+type Model struct {
+	Header []string
+
+	rows       []Row
+	hidden     []bool
+	search     string
+	sortColumn int
+	sortDesc   bool
+}
+
+// NewModel creates a model with every column visible and the original order
+func NewModel(header []string, rows []Row) *Model {
+	return &Model{
+		Header:     header,
+		rows:       rows,
+		hidden:     make([]bool, len(header)),
+		sortColumn: -1,
+	}
+}
+
+// SetRows replaces the events while keeping column, sort and search settings
+func (m *Model) SetRows(rows []Row) {
+	m.rows = rows
+}
+
+// SetSearch filters rows to those containing the text, case-insensitively
+func (m *Model) SetSearch(search string) {
+	m.search = strings.ToLower(search)
+}
+
+// SortBy sorts on a column, reversing the order when it is already sorted on it
+func (m *Model) SortBy(column int) {
+	if column < 0 || column >= len(m.Header) {
+		return
+	}
+	if m.sortColumn == column {
+		m.sortDesc = !m.sortDesc
+		return
+	}
+	m.sortColumn = column
+	m.sortDesc = false
+}
+
+// SortState returns the sorted column (-1 for none) and whether it is descending
+func (m *Model) SortState() (int, bool) {
+	return m.sortColumn, m.sortDesc
+}
+
+// ToggleColumn shows or hides a column. The last visible column cannot be hidden.
+func (m *Model) ToggleColumn(column int) {
+	if column < 0 || column >= len(m.hidden) {
+		return
+	}
+	if !m.hidden[column] && len(m.VisibleColumns()) == 1 {
+		return
+	}
+	m.hidden[column] = !m.hidden[column]
+}
+
+// IsHidden reports whether a column is hidden
+func (m *Model) IsHidden(column int) bool {
+	return m.hidden[column]
+}
+
+// VisibleColumns returns the indexes of the displayed columns
+func (m *Model) VisibleColumns() []int {
+	var columns []int
+	for idx, hidden := range m.hidden {
+		if !hidden {
+			columns = append(columns, idx)
+		}
+	}
+	return columns
+}
+
+// Visible returns the rows matching the search, in display order
+func (m *Model) Visible() []Row {
+	var rows []Row
+	for _, row := range m.rows {
+		if m.matches(row) {
+			rows = append(rows, row)
+		}
+	}
+
+	if m.sortColumn >= 0 {
+		column, desc := m.sortColumn, m.sortDesc
+		sort.SliceStable(rows, func(a, b int) bool {
+			if desc {
+				return cell(rows[b], column) < cell(rows[a], column)
+			}
+			return cell(rows[a], column) < cell(rows[b], column)
+		})
+	}
+	return rows
+}
+
+// matches reports whether the row contains the search text
+func (m *Model) matches(row Row) bool {
+	if m.search == "" {
+		return true
+	}
+	for _, value := range row.Cells {
+		if strings.Contains(strings.ToLower(value), m.search) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(row.Detail), m.search)
+}
+
+// cell returns a cell of the row, or an empty string when missing
+func cell(row Row, column int) string {
+	if column < len(row.Cells) {
+		return row.Cells[column]
+	}
+	return ""
+}
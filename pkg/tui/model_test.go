@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestModel() *Model {
+	return NewModel([]string{"EventName", "Username"}, []Row{
+		{Cells: []string{"PutObject", "carol"}, Detail: `{"bucketName": "reports"}`},
+		{Cells: []string{"AssumeRole", "alice"}},
+		{Cells: []string{"GetObject", "bob"}},
+	})
+}
+
+func firstCells(rows []Row) []string {
+	var cells []string
+	for _, row := range rows {
+		cells = append(cells, row.Cells[0])
+	}
+	return cells
+}
+
+func TestModelSort(t *testing.T) {
+	m := newTestModel()
+
+	if got := firstCells(m.Visible()); !reflect.DeepEqual(got, []string{"PutObject", "AssumeRole", "GetObject"}) {
+		t.Errorf("Expected original order, got %v", got)
+	}
+
+	m.SortBy(1)
+	if got := firstCells(m.Visible()); !reflect.DeepEqual(got, []string{"AssumeRole", "GetObject", "PutObject"}) {
+		t.Errorf("Expected ascending order by user, got %v", got)
+	}
+
+	m.SortBy(1)
+	if got := firstCells(m.Visible()); !reflect.DeepEqual(got, []string{"PutObject", "GetObject", "AssumeRole"}) {
+		t.Errorf("Expected descending order by user, got %v", got)
+	}
+	if column, desc := m.SortState(); column != 1 || !desc {
+		t.Errorf("Expected descending sort on column 1, got %d %v", column, desc)
+	}
+}
+
+func TestModelSearch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		search   string
+		expected []string
+	}{
+		{name: "Empty search", search: "", expected: []string{"PutObject", "AssumeRole", "GetObject"}},
+		{name: "Case-insensitive cell match", search: "OBJECT", expected: []string{"PutObject", "GetObject"}},
+		{name: "Detail match", search: "reports", expected: []string{"PutObject"}},
+		{name: "No match", search: "DeleteTrail", expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestModel()
+			m.SetSearch(tc.search)
+			if got := firstCells(m.Visible()); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestModelToggleColumn(t *testing.T) {
+	m := newTestModel()
+
+	m.ToggleColumn(0)
+	if got := m.VisibleColumns(); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Expected only column 1 visible, got %v", got)
+	}
+
+	// The last visible column stays visible
+	m.ToggleColumn(1)
+	if got := m.VisibleColumns(); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Expected column 1 to stay visible, got %v", got)
+	}
+
+	m.ToggleColumn(0)
+	if got := m.VisibleColumns(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("Expected both columns visible, got %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// AdditionalEventData holds the fields services commonly report in
+// additionalEventData. Service specific fields without a typed counterpart are
+// kept in Extra so nothing is lost when the record is encoded again.
+type AdditionalEventData struct {
+	LoginTo              string   `json:"LoginTo,omitempty"`
+	MobileVersion        string   `json:"MobileVersion,omitempty"`
+	MFAUsed              string   `json:"MFAUsed,omitempty"`
+	MFAIdentifier        string   `json:"MFAIdentifier,omitempty"`
+	CredentialType       string   `json:"CredentialType,omitempty"`
+	SignatureVersion     string   `json:"SignatureVersion,omitempty"`
+	CipherSuite          string   `json:"CipherSuite,omitempty"`
+	AuthenticationMethod string   `json:"AuthenticationMethod,omitempty"`
+	BytesTransferredIn   *float64 `json:"bytesTransferredIn,omitempty"`
+	BytesTransferredOut  *float64 `json:"bytesTransferredOut,omitempty"`
+	XAmzId2              string   `json:"x-amz-id-2,omitempty"`
+
+	Extra map[string]interface{} `json:"-"`
+}
+
+// additionalEventDataFields has the same layout without the custom (un)marshalers
+type additionalEventDataFields AdditionalEventData
+
+func (d *AdditionalEventData) UnmarshalJSON(data []byte) error {
+	var fields additionalEventDataFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, name := range jsonFieldNames(reflect.TypeOf(fields)) {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		fields.Extra = all
+	}
+
+	*d = AdditionalEventData(fields)
+	return nil
+}
+
+func (d AdditionalEventData) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(additionalEventDataFields(d))
+	if err != nil || len(d.Extra) == 0 {
+		return data, err
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for key, value := range d.Extra {
+		if _, typed := all[key]; !typed {
+			all[key] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+// jsonFieldNames returns the JSON keys of the tagged fields of a struct type
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
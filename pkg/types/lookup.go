@@ -0,0 +1,15 @@
+package types
+
+// LookupString walks nested JSON objects (requestParameters, responseElements, ...)
+// and returns the string found at the given path, or an empty string
+func LookupString(v interface{}, path ...string) string {
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	s, _ := v.(string)
+	return s
+}
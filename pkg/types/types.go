@@ -1,10 +1,19 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/guessi/cloudtrail-cli/pkg/accounts"
+	"github.com/guessi/cloudtrail-cli/pkg/geoip"
+	"github.com/guessi/cloudtrail-cli/pkg/iprange"
+	"github.com/guessi/cloudtrail-cli/pkg/redact"
+)
 
 type CloudTrailCliInput struct {
 	Profile           string
 	Region            string
+	EndpointUrl       string
 	StartTime         time.Time
 	EndTime           time.Time
 	EventId           string
@@ -18,68 +27,256 @@ type CloudTrailCliInput struct {
 	ReadOnly          bool
 	MaxResults        int
 	ErrorOnly         bool
+	Insights          bool
 	TruncateUserName  bool
 	TruncateUserAgent bool
+	UserNameLength    int
+	UserAgentLength   int
+	Output            string
+	GraphFormat       string
+	Rules             []string
+	SigmaRules        []string
+	EventFiles        []string
+	IncludeResources  bool
+	Columns           []string
+	IdentityFormat    string
+	PivotOn           string
+	PivotWindow       time.Duration
+	LakeQuery         string
+	EventDataStore    string
+	PrintSQL          bool
+	PollInterval      time.Duration
+	Where             []string
+	SourceIPs         []string
+	GroupBy           []string
+	LogDirectory      string
+	PublicKeyFiles    []string
+	Redactor          *redact.Redactor
+	GeoIP             *geoip.Resolver
+	IPTags            *iprange.Tagger
+	Accounts          *accounts.Aliases
+	ExcludeKnownIPs   bool
 }
 
 // References:
 // - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-event-reference.html
 // - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-event-reference-record-contents.html
 // - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-event-reference-user-identity.html
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-insights-fields.html
+//
+// Optional fields are tagged omitempty/omitzero (or are pointers when their zero
+// value is meaningful) so that a record survives a JSON round trip unchanged.
 
 // AssumedRole
 type SessionIssuer struct {
-	Type        string `json:"type"`
-	PrincipalId string `json:"principalId"`
-	Arn         string `json:"arn"`
-	AccountId   string `json:"accountId"`
-	UserName    string `json:"userName"`
+	Type        string `json:"type,omitempty"`
+	PrincipalId string `json:"principalId,omitempty"`
+	Arn         string `json:"arn,omitempty"`
+	AccountId   string `json:"accountId,omitempty"`
+	UserName    string `json:"userName,omitempty"`
 }
 
 type WebIdFederationData struct {
-	FederatedProvider string      `json:"federatedProvider"`
-	Attributes        interface{} `json:"attributes"`
+	FederatedProvider string                 `json:"federatedProvider,omitempty"`
+	Attributes        map[string]interface{} `json:"attributes,omitempty"`
 }
 
 type Attributes struct {
-	CreationDate     string `json:"creationDate"`
-	MfaAuthenticated string `json:"mfaAuthenticated"`
+	CreationDate     string `json:"creationDate,omitempty"`
+	MfaAuthenticated string `json:"mfaAuthenticated,omitempty"`
 }
 
 type SessionContext struct {
-	SessionIssuer       SessionIssuer       `json:"sessionIssuer"`
-	WebIdFederationData WebIdFederationData `json:"webIdFederationData"`
-	Attributes          Attributes          `json:"attributes"`
+	SessionIssuer       SessionIssuer       `json:"sessionIssuer,omitzero"`
+	WebIdFederationData WebIdFederationData `json:"webIdFederationData,omitzero"`
+	Attributes          Attributes          `json:"attributes,omitzero"`
+	SourceIdentity      string              `json:"sourceIdentity,omitempty"`
+	EC2RoleDelivery     string              `json:"ec2RoleDelivery,omitempty"`
+	AssumedRoot         string              `json:"assumedRoot,omitempty"`
+}
+
+// IAM Identity Center user on whose behalf the call was made
+type OnBehalfOf struct {
+	UserId           string `json:"userId,omitempty"`
+	IdentityStoreArn string `json:"identityStoreArn,omitempty"`
+}
+
+// Credentials issued by a service on behalf of another principal
+type InScopeOf struct {
+	IssuerType          string `json:"issuerType,omitempty"`
+	CredentialsIssuedTo string `json:"credentialsIssuedTo,omitempty"`
+	IssuerAccountId     string `json:"issuerAccountId,omitempty"`
+	SourceArn           string `json:"sourceArn,omitempty"`
+	SourceAccount       string `json:"sourceAccount,omitempty"`
 }
 
 type UserIdentity struct {
-	Type           string         `json:"type"`
-	PrincipalId    string         `json:"principalId"`
-	Arn            string         `json:"arn"`
-	AccountId      string         `json:"accountId"`
-	AccessKeyId    string         `json:"accessKeyId"`
-	UserName       string         `json:"userName,omitempty"`
-	SessionContext SessionContext `json:"sessionContext,omitempty"`
-	InvokedBy      string         `json:"invokedBy"`
+	Type             string         `json:"type,omitempty"`
+	PrincipalId      string         `json:"principalId,omitempty"`
+	Arn              string         `json:"arn,omitempty"`
+	AccountId        string         `json:"accountId,omitempty"`
+	AccessKeyId      string         `json:"accessKeyId,omitempty"`
+	UserName         string         `json:"userName,omitempty"`
+	SessionContext   SessionContext `json:"sessionContext,omitzero"`
+	InvokedBy        string         `json:"invokedBy,omitempty"`
+	IdentityProvider string         `json:"identityProvider,omitempty"`
+	CredentialId     string         `json:"credentialId,omitempty"`
+	OnBehalfOf       *OnBehalfOf    `json:"onBehalfOf,omitempty"`
+	InScopeOf        *InScopeOf     `json:"inScopeOf,omitempty"`
+}
+
+// Resource accessed by the event
+type Resource struct {
+	ARN       string `json:"ARN,omitempty"`
+	AccountId string `json:"accountId,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type TLSDetails struct {
+	TLSVersion               string `json:"tlsVersion,omitempty"`
+	CipherSuite              string `json:"cipherSuite,omitempty"`
+	ClientProvidedHostHeader string `json:"clientProvidedHostHeader,omitempty"`
+}
+
+// Present when the event was updated or delivered late
+type Addendum struct {
+	Reason            string `json:"reason,omitempty"`
+	UpdatedFields     string `json:"updatedFields,omitempty"`
+	OriginalRequestID string `json:"originalRequestID,omitempty"`
+	OriginalEventID   string `json:"originalEventID,omitempty"`
+}
+
+// Insights
+type InsightStatistic struct {
+	Average     *float64 `json:"average,omitempty"`
+	ErrorCount  *float64 `json:"errorCount,omitempty"`
+	Count       *float64 `json:"count,omitempty"`
+	SampleCount *float64 `json:"sampleCount,omitempty"`
+}
+
+type InsightStatistics struct {
+	Baseline         InsightStatistic `json:"baseline,omitzero"`
+	Insight          InsightStatistic `json:"insight,omitzero"`
+	InsightDuration  *float64         `json:"insightDuration,omitempty"`
+	BaselineDuration *float64         `json:"baselineDuration,omitempty"`
+}
+
+type InsightAttributeValue struct {
+	Value   string   `json:"value,omitempty"`
+	Average *float64 `json:"average,omitempty"`
+}
+
+type InsightAttribution struct {
+	Attribute string                  `json:"attribute,omitempty"`
+	Insight   []InsightAttributeValue `json:"insight,omitempty"`
+	Baseline  []InsightAttributeValue `json:"baseline,omitempty"`
+}
+
+type InsightContext struct {
+	Statistics   InsightStatistics    `json:"statistics,omitzero"`
+	Attributions []InsightAttribution `json:"attributions,omitempty"`
+}
+
+type InsightDetails struct {
+	State          string         `json:"state,omitempty"`
+	EventSource    string         `json:"eventSource,omitempty"`
+	EventName      string         `json:"eventName,omitempty"`
+	InsightType    string         `json:"insightType,omitempty"`
+	ErrorCode      string         `json:"errorCode,omitempty"`
+	InsightContext InsightContext `json:"insightContext,omitzero"`
 }
 
 type CloudTrailEvent struct {
-	EventVersion       string       `json:"eventVersion"`
-	UserIdentity       UserIdentity `json:"userIdentity"`
-	EventTime          string       `json:"eventTime"`
-	EventSource        string       `json:"eventSource"`
-	EventName          string       `json:"eventName"`
-	AwsRegion          string       `json:"awsRegion"`
-	SourceIPAddress    string       `json:"sourceIPAddress"`
-	UserAgent          string       `json:"userAgent"`
-	ErrorCode          string       `json:"errorCode,omitempty"`
-	ErrorMessage       string       `json:"errorMessage,omitempty"`
-	RequestParameters  interface{}  `json:"requestParameters"`
-	ResponseElements   interface{}  `json:"responseElements"`
-	RequestId          string       `json:"requestID"`
-	EventId            string       `json:"eventID"`
-	ReadOnly           bool         `json:"readOnly"`
-	ManagementEvent    bool         `json:"managementEvent"`
-	RecipientAccountId string       `json:"recipientAccountId"`
-	EventCategory      string       `json:"eventCategory"`
+	EventVersion                 string                 `json:"eventVersion,omitempty"`
+	UserIdentity                 UserIdentity           `json:"userIdentity,omitzero"`
+	EventTime                    string                 `json:"eventTime,omitempty"`
+	EventSource                  string                 `json:"eventSource,omitempty"`
+	EventName                    string                 `json:"eventName,omitempty"`
+	AwsRegion                    string                 `json:"awsRegion,omitempty"`
+	SourceIPAddress              string                 `json:"sourceIPAddress,omitempty"`
+	UserAgent                    string                 `json:"userAgent,omitempty"`
+	ErrorCode                    string                 `json:"errorCode,omitempty"`
+	ErrorMessage                 string                 `json:"errorMessage,omitempty"`
+	RequestParameters            interface{}            `json:"requestParameters,omitempty"`
+	ResponseElements             interface{}            `json:"responseElements,omitempty"`
+	AdditionalEventData          *AdditionalEventData   `json:"additionalEventData,omitempty"`
+	RequestId                    string                 `json:"requestID,omitempty"`
+	EventId                      string                 `json:"eventID,omitempty"`
+	ReadOnly                     *bool                  `json:"readOnly,omitempty"`
+	Resources                    []Resource             `json:"resources,omitempty"`
+	EventType                    string                 `json:"eventType,omitempty"`
+	ApiVersion                   string                 `json:"apiVersion,omitempty"`
+	ManagementEvent              *bool                  `json:"managementEvent,omitempty"`
+	RecipientAccountId           string                 `json:"recipientAccountId,omitempty"`
+	ServiceEventDetails          map[string]interface{} `json:"serviceEventDetails,omitempty"`
+	SharedEventId                string                 `json:"sharedEventID,omitempty"`
+	VpcEndpointId                string                 `json:"vpcEndpointId,omitempty"`
+	VpcEndpointAccountId         string                 `json:"vpcEndpointAccountId,omitempty"`
+	EventCategory                string                 `json:"eventCategory,omitempty"`
+	Addendum                     *Addendum              `json:"addendum,omitempty"`
+	SessionCredentialFromConsole string                 `json:"sessionCredentialFromConsole,omitempty"`
+	EdgeDeviceDetails            map[string]interface{} `json:"edgeDeviceDetails,omitempty"`
+	TLSDetails                   *TLSDetails            `json:"tlsDetails,omitempty"`
+	InsightDetails               *InsightDetails        `json:"insightDetails,omitempty"`
+}
+
+// Null stands for a requestParameters or responseElements member recorded as
+// an explicit null. Unlike an absent member, it is encoded back as null.
+var Null = json.RawMessage("null")
+
+// IsNull reports whether a value is absent or an explicit null
+func IsNull(v interface{}) bool {
+	raw, ok := v.(json.RawMessage)
+	return v == nil || ok && string(raw) == "null"
+}
+
+// cloudTrailEventFields has the same layout without the custom unmarshaler
+type cloudTrailEventFields CloudTrailEvent
+
+func (e *CloudTrailEvent) UnmarshalJSON(data []byte) error {
+	// requestParameters and responseElements are decoded on their own to tell
+	// an explicit null from an absent member
+	fields := struct {
+		*cloudTrailEventFields
+		RequestParameters json.RawMessage `json:"requestParameters"`
+		ResponseElements  json.RawMessage `json:"responseElements"`
+	}{cloudTrailEventFields: (*cloudTrailEventFields)(e)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var err error
+	if e.RequestParameters, err = decodeNullable(fields.RequestParameters); err != nil {
+		return err
+	}
+	e.ResponseElements, err = decodeNullable(fields.ResponseElements)
+	return err
+}
+
+// decodeNullable decodes a member, returning nil when absent and Null when null
+func decodeNullable(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if string(raw) == "null" {
+		return Null, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LookupResource is a resource reference LookupEvents returns next to the record
+type LookupResource struct {
+	ResourceType string
+	ResourceName string
+}
+
+// Event is a parsed record together with the metadata LookupEvents returns alongside it
+type Event struct {
+	Record    *CloudTrailEvent
+	Username  string
+	Resources []LookupResource
 }
@@ -0,0 +1,218 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// canonicalJSON re-encodes a document with sorted keys and normalized numbers
+// and whitespace, so documents holding the same members compare byte for byte
+func canonicalJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func TestCloudTrailEventRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name   string
+		record string
+	}{
+		{
+			name: "S3 data event",
+			record: `{
+				"eventVersion": "1.10",
+				"userIdentity": {
+					"type": "AssumedRole",
+					"principalId": "AROAEXAMPLE:alice",
+					"arn": "arn:aws:sts::123456789012:assumed-role/Reader/alice",
+					"accountId": "123456789012",
+					"accessKeyId": "ASIAEXAMPLE000000000",
+					"sessionContext": {
+						"sessionIssuer": {"type": "Role", "principalId": "AROAEXAMPLE", "arn": "arn:aws:iam::123456789012:role/Reader", "accountId": "123456789012", "userName": "Reader"},
+						"attributes": {"creationDate": "2025-05-12T00:00:00Z", "mfaAuthenticated": "false"},
+						"sourceIdentity": "alice@example.com"
+					},
+					"credentialId": "EXAMPLEcredentialId"
+				},
+				"eventTime": "2025-05-12T00:01:00Z",
+				"eventSource": "s3.amazonaws.com",
+				"eventName": "GetObject",
+				"awsRegion": "us-east-1",
+				"sourceIPAddress": "198.51.100.1",
+				"userAgent": "aws-cli/2.15.0",
+				"requestParameters": {"bucketName": "assets", "key": "a.txt", "Host": "assets.s3.amazonaws.com"},
+				"responseElements": null,
+				"additionalEventData": {
+					"SignatureVersion": "SigV4",
+					"CipherSuite": "TLS_AES_128_GCM_SHA256",
+					"bytesTransferredIn": 0,
+					"bytesTransferredOut": 1024,
+					"AuthenticationMethod": "AuthHeader",
+					"x-amz-id-2": "EXAMPLE",
+					"SSEApplied": "Default_SSE_S3"
+				},
+				"requestID": "EXAMPLE",
+				"eventID": "7c6e6f1d-3b1c-4b0e-9f7e-1a2b3c4d5e6f",
+				"readOnly": true,
+				"resources": [
+					{"type": "AWS::S3::Object", "ARN": "arn:aws:s3:::assets/a.txt"},
+					{"accountId": "123456789012", "type": "AWS::S3::Bucket", "ARN": "arn:aws:s3:::assets"}
+				],
+				"eventType": "AwsApiCall",
+				"managementEvent": false,
+				"recipientAccountId": "123456789012",
+				"sharedEventID": "0b9b1ba0-0000-4000-8000-000000000000",
+				"vpcEndpointId": "vpce-0123456789abcdef0",
+				"vpcEndpointAccountId": "123456789012",
+				"eventCategory": "Data",
+				"tlsDetails": {"tlsVersion": "TLSv1.3", "cipherSuite": "TLS_AES_128_GCM_SHA256", "clientProvidedHostHeader": "assets.s3.amazonaws.com"}
+			}`,
+		},
+		{
+			name: "Identity Center console sign-in",
+			record: `{
+				"eventVersion": "1.09",
+				"userIdentity": {
+					"type": "IdentityCenterUser",
+					"accountId": "123456789012",
+					"onBehalfOf": {"userId": "94482488-3041-7026-18f3-be45837cd0e4", "identityStoreArn": "arn:aws:identitystore::123456789012:identitystore/d-9067642ac7"},
+					"credentialId": "EXAMPLEVHULjJdTUdPJfofVa1sufHDoj7aYcOYcxFVllWR_Whr1fEXAMPLE"
+				},
+				"eventTime": "2025-05-12T00:02:00Z",
+				"eventSource": "signin.amazonaws.com",
+				"eventName": "ConsoleLogin",
+				"awsRegion": "us-east-1",
+				"sourceIPAddress": "203.0.113.1",
+				"userAgent": "Mozilla/5.0",
+				"requestParameters": null,
+				"responseElements": {"ConsoleLogin": "Success"},
+				"additionalEventData": {"LoginTo": "https://console.aws.amazon.com", "MobileVersion": "No", "MFAUsed": "Yes"},
+				"eventID": "11111111-2222-4333-8444-555555555555",
+				"readOnly": false,
+				"eventType": "AwsConsoleSignIn",
+				"managementEvent": true,
+				"recipientAccountId": "123456789012",
+				"eventCategory": "Management",
+				"sessionCredentialFromConsole": "true",
+				"serviceEventDetails": {"UserAuthentication": "Success"},
+				"addendum": {"reason": "DELIVERY_DELAY", "originalEventID": "11111111-2222-4333-8444-555555555555"}
+			}`,
+		},
+		{
+			name: "Insights event",
+			record: `{
+				"eventVersion": "1.08",
+				"eventTime": "2025-05-12T00:05:00Z",
+				"awsRegion": "us-east-1",
+				"eventID": "aa11bb22-cc33-4d44-8e55-ff6677889900",
+				"eventType": "AwsCloudTrailInsight",
+				"recipientAccountId": "123456789012",
+				"sharedEventID": "12345678-90ab-4cde-8f01-234567890abc",
+				"insightDetails": {
+					"state": "Start",
+					"eventSource": "ssm.amazonaws.com",
+					"eventName": "UpdateInstanceAssociationStatus",
+					"insightType": "ApiCallRateInsight",
+					"insightContext": {
+						"statistics": {
+							"baseline": {"average": 0.0020},
+							"insight": {"average": 6},
+							"insightDuration": 1,
+							"baselineDuration": 11459
+						},
+						"attributions": [
+							{
+								"attribute": "userIdentityArn",
+								"insight": [{"value": "arn:aws:sts::123456789012:assumed-role/Ops/i-0abc", "average": 6}],
+								"baseline": [{"value": "arn:aws:sts::123456789012:assumed-role/Ops/i-0abc", "average": 0.002}]
+							}
+						]
+					}
+				},
+				"eventCategory": "Insight"
+			}`,
+		},
+		{
+			name: "Explicit null request parameters and response elements",
+			record: `{
+				"eventID": "22222222-3333-4444-8555-666666666666",
+				"eventName": "GetCallerIdentity",
+				"requestParameters": null,
+				"responseElements": null
+			}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var event CloudTrailEvent
+			if err := json.Unmarshal([]byte(tc.record), &event); err != nil {
+				t.Fatalf("failed to decode record: %v", err)
+			}
+			encoded, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("failed to encode record: %v", err)
+			}
+
+			original, err := canonicalJSON([]byte(tc.record))
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundTripped, err := canonicalJSON(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(original, roundTripped) {
+				t.Errorf("round trip changed the record:\n got: %s\nwant: %s", encoded, tc.record)
+			}
+		})
+	}
+}
+
+func TestAdditionalEventDataExtra(t *testing.T) {
+	var data AdditionalEventData
+	if err := json.Unmarshal([]byte(`{"MFAUsed": "No", "SSEApplied": "SSE_KMS"}`), &data); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if data.MFAUsed != "No" {
+		t.Errorf("MFAUsed = %q, want No", data.MFAUsed)
+	}
+	if len(data.Extra) != 1 || data.Extra["SSEApplied"] != "SSE_KMS" {
+		t.Errorf("Extra = %v, want only SSEApplied", data.Extra)
+	}
+}
+
+func TestLookupString(t *testing.T) {
+	v := map[string]interface{}{
+		"credentials": map[string]interface{}{"accessKeyId": "ASIAEXAMPLE"},
+		"count":       1,
+	}
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		path     []string
+		expected string
+	}{
+		{"Nested string", v, []string{"credentials", "accessKeyId"}, "ASIAEXAMPLE"},
+		{"Missing key", v, []string{"credentials", "sessionToken"}, ""},
+		{"Non-string value", v, []string{"count"}, ""},
+		{"Path through non-object", v, []string{"count", "value"}, ""},
+		{"Event without responseElements", nil, []string{"ConsoleLogin"}, ""},
+		{"Empty path", "Success", nil, "Success"},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := LookupString(tc.value, tc.path...); got != tc.expected {
+				t.Errorf("LookupString(%v) = %q, want %q", tc.path, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Tool families user agents are classified into
+const (
+	Console            = "Console"
+	AWSCLIv1           = "AWS CLI v1"
+	AWSCLIv2           = "AWS CLI v2"
+	Boto3              = "boto3"
+	GoSDKv1            = "Go SDK v1"
+	GoSDKv2            = "Go SDK v2"
+	JavaSDK            = "Java SDK"
+	Terraform          = "Terraform"
+	CloudFormation     = "CloudFormation"
+	Pulumi             = "Pulumi"
+	AWSServiceInternal = "AWS service internal"
+	Other              = "Other"
+)
+
+// Client is the tool a user agent belongs to
+type Client struct {
+	Family  string
+	Version string
+}
+
+// String returns the family followed by the version, when known
+func (c Client) String() string {
+	if c.Version == "" {
+		return c.Family
+	}
+	return c.Family + " " + c.Version
+}
+
+var (
+	// serviceHost matches the service principals reported as user agent of
+	// calls made by AWS services on behalf of a user
+	serviceHost = regexp.MustCompile(`(?i)^[a-z0-9-]+(\.[a-z0-9-]+)*\.amazonaws\.com$`)
+
+	// consoleHost matches the console endpoints, e.g. console.ec2.amazonaws.com
+	consoleHost = regexp.MustCompile(`(?i)^(console(\.[a-z0-9-]+)?|[a-z0-9-]+\.console|signin)\.amazonaws\.com$`)
+)
+
+// tokenPattern compiles the pattern of a product/version token
+func tokenPattern(product string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[\s\[(,])` + regexp.QuoteMeta(product) + `/([^\s\]),;]+)`)
+}
+
+var (
+	awsCliToken      = tokenPattern("aws-cli")
+	boto3Token       = tokenPattern("Boto3")
+	goSDKv2Token     = tokenPattern("aws-sdk-go-v2")
+	goSDKv1Token     = tokenPattern("aws-sdk-go")
+	javaSDKToken     = tokenPattern("aws-sdk-java")
+	terraformToken   = tokenPattern("Terraform")
+	pulumiToken      = tokenPattern("Pulumi")
+	pulumiAWSToken   = tokenPattern("pulumi-aws")
+	s3ConsoleToken   = tokenPattern("S3Console")
+	awsInternalToken = tokenPattern("aws-internal")
+)
+
+// version returns the version of a product token, and whether it was found
+func version(token *regexp.Regexp, userAgent string) (string, bool) {
+	m := token.FindStringSubmatch(userAgent)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// Classify returns the tool family and version of a user agent. An empty user
+// agent yields an empty Client, an unrecognized one the Other family.
+func Classify(userAgent string) Client {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return Client{}
+	}
+
+	// Browsers only call AWS through the console
+	if consoleHost.MatchString(userAgent) || strings.HasPrefix(userAgent, "Mozilla/") {
+		return Client{Family: Console}
+	}
+	if v, ok := version(s3ConsoleToken, userAgent); ok {
+		return Client{Family: Console, Version: v}
+	}
+
+	// Infrastructure as code tools embed the SDK they are built with, so they
+	// are checked first
+	if v, ok := version(pulumiToken, userAgent); ok {
+		return Client{Family: Pulumi, Version: v}
+	}
+	if v, ok := version(pulumiAWSToken, userAgent); ok {
+		return Client{Family: Pulumi, Version: v}
+	}
+	if v, ok := version(terraformToken, userAgent); ok {
+		return Client{Family: Terraform, Version: v}
+	}
+	if strings.EqualFold(userAgent, "cloudformation.amazonaws.com") {
+		return Client{Family: CloudFormation}
+	}
+
+	if v, ok := version(awsCliToken, userAgent); ok {
+		if strings.HasPrefix(v, "1.") {
+			return Client{Family: AWSCLIv1, Version: v}
+		}
+		return Client{Family: AWSCLIv2, Version: v}
+	}
+	if v, ok := version(boto3Token, userAgent); ok {
+		return Client{Family: Boto3, Version: v}
+	}
+	if v, ok := version(goSDKv2Token, userAgent); ok {
+		return Client{Family: GoSDKv2, Version: v}
+	}
+	if v, ok := version(goSDKv1Token, userAgent); ok {
+		return Client{Family: GoSDKv1, Version: v}
+	}
+
+	// Services calling on their own behalf use an internal Java SDK build
+	if _, ok := version(awsInternalToken, userAgent); ok {
+		return Client{Family: AWSServiceInternal}
+	}
+	if v, ok := version(javaSDKToken, userAgent); ok {
+		return Client{Family: JavaSDK, Version: v}
+	}
+	if userAgent == "AWS Internal" || serviceHost.MatchString(userAgent) {
+		return Client{Family: AWSServiceInternal}
+	}
+	return Client{Family: Other}
+}
@@ -0,0 +1,57 @@
+package useragent
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyCorpus(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "user-agents.tsv"))
+	if err != nil {
+		t.Fatalf("failed to open corpus: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, "\t", 3)
+		if len(fields) != 3 {
+			t.Fatalf("line %d: expected family, version and user agent", line)
+		}
+		expected := Client{Family: fields[0], Version: fields[1]}
+		if got := Classify(fields[2]); got != expected {
+			t.Errorf("line %d: Classify(%q) = %+v, want %+v", line, fields[2], got, expected)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+}
+
+func TestClientString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   Client
+		expected string
+	}{
+		{name: "Family and version", client: Client{Family: AWSCLIv2, Version: "2.15.30"}, expected: "AWS CLI v2 2.15.30"},
+		{name: "Family only", client: Client{Family: Console}, expected: "Console"},
+		{name: "Empty user agent", client: Classify(" "), expected: ""},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.client.String(); got != tc.expected {
+				t.Errorf("String() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
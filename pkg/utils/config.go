@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/guessi/cloudtrail-cli/pkg/config"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"gopkg.in/yaml.v3"
+)
+
+// RenderQueries lists the named queries of the configuration
+func RenderQueries(cfg *config.Config) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Query", "Description"})
+
+	for _, name := range cfg.QueryNames() {
+		t.AppendRow(table.Row{name, cfg.Queries[name].Description})
+	}
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
+
+// RenderConfig prints the effective settings as YAML
+func RenderConfig(path string, cfg config.Config) error {
+	source := path
+	if _, err := os.Stat(path); err != nil {
+		source += " (not found)"
+	}
+	fmt.Printf("# %s\n", source)
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("unable to render configuration: %w", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/detect"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// parseCloudTrailEvents parses every event payload, skipping the ones that fail
+func parseCloudTrailEvents(events []ctypes.Event) []*types.CloudTrailEvent {
+	parsed := make([]*types.CloudTrailEvent, 0, len(events))
+	for _, event := range events {
+		cloudTrailEvent, err := parseCloudTrailEvent(event)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, cloudTrailEvent)
+	}
+	return parsed
+}
+
+// renderFindings creates and renders the detection findings table
+func renderFindings(w io.Writer, findings []detect.Finding, config types.CloudTrailCliInput) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{
+		"Severity", "Rule", "EventTime", "Principal", "SourceIPAddress", "Summary", "EventIds",
+	})
+
+	var rows []table.Row
+	for _, f := range findings {
+		rows = append(rows, table.Row{
+			f.Severity, f.Rule, f.EventTime, f.Principal, f.SourceIP, f.Summary, strings.Join(f.EventIds, "\n"),
+		})
+	}
+	t.AppendRows(displayRows(config, rows))
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
+
+// detectHandlerWithLookup allows injection of LookupEvents function for testing
+func detectHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+
+	rules, unknown := detect.SelectRules(i.Rules)
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown detection rule(s): %s", strings.Join(unknown, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	input, err := buildCloudTrailInput(i)
+	if err != nil {
+		return err
+	}
+
+	events, err := lookupFunc(ctx, svc, input, i.MaxResults)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+
+	renderFindings(os.Stdout, detect.Evaluate(rules, parseCloudTrailEvents(events)), i)
+	return nil
+}
+
+// DetectHandler evaluates the built-in security detection rules over the retrieved events
+func DetectHandler(i types.CloudTrailCliInput) error {
+	return detectHandlerWithLookup(i, LookupEvents)
+}
+
+// RenderDetectionRules prints the library of built-in detection rules
+func RenderDetectionRules() {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Rule", "Severity", "Description"})
+
+	for _, rule := range detect.BuiltinRules {
+		t.AppendRow(table.Row{rule.Name(), rule.Severity(), rule.Description()})
+	}
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
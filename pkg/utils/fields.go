@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/geoip"
+	"github.com/guessi/cloudtrail-cli/pkg/iprange"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/guessi/cloudtrail-cli/pkg/useragent"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// eventField is a named value of an event that can be displayed as a column and
+// used in --where filters. Multi-valued fields return one entry per value.
+type eventField struct {
+	Name   string
+	Values func(e *types.Event, config types.CloudTrailCliInput) []string
+}
+
+// single adapts a single-valued getter to the eventField signature
+func single(get func(r *types.CloudTrailEvent) string) func(e *types.Event, config types.CloudTrailCliInput) []string {
+	return func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{get(e.Record)}
+	}
+}
+
+// location adapts a getter of the GeoIP location of the source IP address
+func location(get func(l geoip.Location) string) func(e *types.Event, config types.CloudTrailCliInput) []string {
+	return func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{get(config.GeoIP.Lookup(e.Record.SourceIPAddress))}
+	}
+}
+
+// ipTag adapts a getter of the tag of the source IP address
+func ipTag(get func(t iprange.Tag) string) func(e *types.Event, config types.CloudTrailCliInput) []string {
+	return func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{get(config.IPTags.Lookup(e.Record.SourceIPAddress))}
+	}
+}
+
+// eventFields lists every field available to --columns and --where
+var eventFields = []eventField{
+	{"EventId", single(func(r *types.CloudTrailEvent) string { return r.EventId })},
+	{"EventName", single(func(r *types.CloudTrailEvent) string { return r.EventName })},
+	{"EventTime", single(func(r *types.CloudTrailEvent) string { return r.EventTime })},
+	{"Username", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{formatIdentityName(e.Record.UserIdentity, config.IdentityFormat)}
+	}},
+	{"IdentityType", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.Type })},
+	{"EventSource", single(func(r *types.CloudTrailEvent) string { return r.EventSource })},
+	{"UserAgent", single(func(r *types.CloudTrailEvent) string { return r.UserAgent })},
+	{"Client", single(func(r *types.CloudTrailEvent) string { return useragent.Classify(r.UserAgent).String() })},
+	{"ClientFamily", single(func(r *types.CloudTrailEvent) string { return useragent.Classify(r.UserAgent).Family })},
+	{"SourceIPAddress", single(func(r *types.CloudTrailEvent) string { return r.SourceIPAddress })},
+	{"AccessKeyId", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.AccessKeyId })},
+	{"ErrorCode", single(func(r *types.CloudTrailEvent) string { return r.ErrorCode })},
+	{"ReadOnly", single(func(r *types.CloudTrailEvent) string { return formatOptionalBool(r.ReadOnly) })},
+	{"ErrorMessage", single(func(r *types.CloudTrailEvent) string { return r.ErrorMessage })},
+	{"AwsRegion", single(func(r *types.CloudTrailEvent) string { return r.AwsRegion })},
+	{"EventType", single(func(r *types.CloudTrailEvent) string { return r.EventType })},
+	{"EventCategory", single(func(r *types.CloudTrailEvent) string { return r.EventCategory })},
+	{"EventVersion", single(func(r *types.CloudTrailEvent) string { return r.EventVersion })},
+	{"ApiVersion", single(func(r *types.CloudTrailEvent) string { return r.ApiVersion })},
+	{"ManagementEvent", single(func(r *types.CloudTrailEvent) string { return formatOptionalBool(r.ManagementEvent) })},
+	{"RecipientAccountId", single(func(r *types.CloudTrailEvent) string { return r.RecipientAccountId })},
+	{"RequestId", single(func(r *types.CloudTrailEvent) string { return r.RequestId })},
+	{"SharedEventId", single(func(r *types.CloudTrailEvent) string { return r.SharedEventId })},
+	{"VpcEndpointId", single(func(r *types.CloudTrailEvent) string { return r.VpcEndpointId })},
+	{"SessionCredentialFromConsole", single(func(r *types.CloudTrailEvent) string { return r.SessionCredentialFromConsole })},
+	{"UserIdentityArn", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.Arn })},
+	{"AccountId", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.AccountId })},
+	{"AccountAlias", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{config.Accounts.Name(e.Record.UserIdentity.AccountId)}
+	}},
+	{"RecipientAccountAlias", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{config.Accounts.Name(e.Record.RecipientAccountId)}
+	}},
+	{"PrincipalId", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.PrincipalId })},
+	{"SessionIssuerUserName", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.SessionContext.SessionIssuer.UserName })},
+	{"SessionIssuerArn", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.SessionContext.SessionIssuer.Arn })},
+	{"SessionIssuerAccountId", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.SessionContext.SessionIssuer.AccountId })},
+	{"SessionIssuerAccountAlias", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		return []string{config.Accounts.Name(e.Record.UserIdentity.SessionContext.SessionIssuer.AccountId)}
+	}},
+	{"MfaAuthenticated", single(func(r *types.CloudTrailEvent) string {
+		return r.UserIdentity.SessionContext.Attributes.MfaAuthenticated
+	})},
+	{"InvokedBy", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.InvokedBy })},
+	{"CredentialId", single(func(r *types.CloudTrailEvent) string { return r.UserIdentity.CredentialId })},
+	{"TLSVersion", single(func(r *types.CloudTrailEvent) string {
+		if r.TLSDetails == nil {
+			return ""
+		}
+		return r.TLSDetails.TLSVersion
+	})},
+	{"LoginResult", single(func(r *types.CloudTrailEvent) string { return types.LookupString(r.ResponseElements, "ConsoleLogin") })},
+	{"MFAUsed", single(func(r *types.CloudTrailEvent) string {
+		if r.AdditionalEventData == nil {
+			return ""
+		}
+		return r.AdditionalEventData.MFAUsed
+	})},
+	{"Country", location(func(l geoip.Location) string { return l.Country })},
+	{"City", location(func(l geoip.Location) string { return l.City })},
+	{"ASN", location(func(l geoip.Location) string { return l.ASN })},
+	{"ASOrganization", location(func(l geoip.Location) string { return l.ASOrganization })},
+	{"SourceIPService", ipTag(func(t iprange.Tag) string { return t.Service })},
+	{"SourceIPRegion", ipTag(func(t iprange.Tag) string { return t.Region })},
+	{"SourceIPLabel", ipTag(func(t iprange.Tag) string { return t.Label })},
+	{"LookupUsername", func(e *types.Event, config types.CloudTrailCliInput) []string { return []string{e.Username} }},
+	{"Resources", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		var values []string
+		for _, resource := range eventResources(e) {
+			values = append(values, strings.TrimSpace(resource.ResourceType+" "+resource.ResourceName))
+		}
+		return values
+	}},
+	{"ResourceType", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		var values []string
+		for _, resource := range eventResources(e) {
+			values = append(values, resource.ResourceType)
+		}
+		return values
+	}},
+	{"ResourceName", func(e *types.Event, config types.CloudTrailCliInput) []string {
+		var values []string
+		for _, resource := range eventResources(e) {
+			values = append(values, resource.ResourceName)
+		}
+		return values
+	}},
+}
+
+// defaultColumns is the table layout used when --columns is not set
+var defaultColumns = []string{
+	"EventId", "EventName", "EventTime", "Username", "IdentityType", "EventSource",
+	"UserAgent", "SourceIPAddress", "AccessKeyId", "ErrorCode", "ReadOnly",
+	"Resources",
+}
+
+// findEventField looks up a field by name, case-insensitively
+func findEventField(name string) (eventField, bool) {
+	for _, field := range eventFields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return eventField{}, false
+}
+
+// resolveColumns returns the fields to display, falling back to the default layout
+func resolveColumns(names []string) ([]eventField, error) {
+	if len(names) == 0 {
+		names = defaultColumns
+	}
+
+	columns := make([]eventField, 0, len(names))
+	for _, name := range names {
+		field, ok := findEventField(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q, run 'columns' to list available fields", name)
+		}
+		columns = append(columns, field)
+	}
+	return columns, nil
+}
+
+// columnNames returns the canonical names of the selected columns
+func columnNames(config types.CloudTrailCliInput) []string {
+	columns, _ := resolveColumns(config.Columns)
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// eventResources returns the resources reported by LookupEvents, or the ones in the record
+func eventResources(e *types.Event) []types.LookupResource {
+	if len(e.Resources) > 0 {
+		return e.Resources
+	}
+	resources := make([]types.LookupResource, 0, len(e.Record.Resources))
+	for _, resource := range e.Record.Resources {
+		resources = append(resources, types.LookupResource{ResourceType: resource.Type, ResourceName: resource.ARN})
+	}
+	return resources
+}
+
+// newEvent attaches the metadata returned by LookupEvents to a parsed record
+func newEvent(event ctypes.Event, record *types.CloudTrailEvent) *types.Event {
+	e := &types.Event{Record: record, Username: aws.ToString(event.Username)}
+	for _, resource := range event.Resources {
+		e.Resources = append(e.Resources, types.LookupResource{
+			ResourceType: aws.ToString(resource.ResourceType),
+			ResourceName: aws.ToString(resource.ResourceName),
+		})
+	}
+	return e
+}
+
+// whereClause is a client-side filter of the form Field=pattern or Field!=pattern
+type whereClause struct {
+	field   eventField
+	negate  bool
+	value   string
+	pattern *regexp.Regexp
+}
+
+// whereExpression splits "Field=pattern" / "Field!=pattern"
+var whereExpression = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9]*)\s*(!?=)(.*)$`)
+
+// parseWhereClauses parses --where expressions. Patterns are case-insensitive
+// and support "*" wildcards; an empty pattern matches empty values.
+func parseWhereClauses(expressions []string) ([]whereClause, error) {
+	clauses := make([]whereClause, 0, len(expressions))
+	for _, expression := range expressions {
+		m := whereExpression.FindStringSubmatch(expression)
+		if m == nil {
+			return nil, fmt.Errorf("invalid --where expression %q: expected Field=value or Field!=value", expression)
+		}
+		field, ok := findEventField(m[1])
+		if !ok {
+			return nil, fmt.Errorf("invalid --where expression %q: unknown field %q", expression, m[1])
+		}
+		value := strings.TrimSpace(m[3])
+		clauses = append(clauses, whereClause{
+			field:   field,
+			negate:  m[2] == "!=",
+			value:   value,
+			pattern: wildcardPattern(value),
+		})
+	}
+	return clauses, nil
+}
+
+// wildcardPattern compiles a case-insensitive pattern where "*" matches any characters
+func wildcardPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for idx, part := range parts {
+		parts[idx] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+}
+
+// matches reports whether any value of the field satisfies the clause
+func (w whereClause) matches(e *types.Event, config types.CloudTrailCliInput) bool {
+	values := w.field.Values(e, config)
+	if len(values) == 0 {
+		values = []string{""}
+	}
+
+	matched := false
+	for _, value := range values {
+		if w.pattern.MatchString(value) {
+			matched = true
+			break
+		}
+	}
+	return matched != w.negate
+}
+
+// matchesAll reports whether the event satisfies every clause
+func matchesAll(clauses []whereClause, e *types.Event, config types.CloudTrailCliInput) bool {
+	for _, clause := range clauses {
+		if !clause.matches(e, config) {
+			return false
+		}
+	}
+	return true
+}
+
+// RenderColumns lists the fields available to --columns and --where
+func RenderColumns() {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Field", "Default"})
+
+	for _, field := range eventFields {
+		t.AppendRow(table.Row{field.Name, containsString(defaultColumns, field.Name)})
+	}
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func newLookupEvent(record string, username string, resources ...ctypes.Resource) ctypes.Event {
+	return ctypes.Event{
+		CloudTrailEvent: aws.String(record),
+		Username:        aws.String(username),
+		Resources:       resources,
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	testCases := []struct {
+		name        string
+		columns     []string
+		expected    int
+		expectError bool
+	}{
+		{name: "Default layout", columns: nil, expected: len(defaultColumns)},
+		{name: "Case-insensitive names", columns: []string{"eventname", " Resources "}, expected: 2},
+		{name: "Unknown column", columns: []string{"EventName", "Bogus"}, expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			columns, err := resolveColumns(tc.columns)
+			if tc.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(columns) != tc.expected {
+				t.Errorf("Expected %d columns, got %d", tc.expected, len(columns))
+			}
+		})
+	}
+}
+
+func TestParseWhereClauses(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expressions []string
+		expectError bool
+	}{
+		{name: "Equality", expressions: []string{"EventName=PutObject"}},
+		{name: "Negation with wildcard", expressions: []string{"ResourceName!=arn:aws:s3:::logs-*"}},
+		{name: "Empty value", expressions: []string{"ErrorCode="}},
+		{name: "Missing operator", expressions: []string{"EventName"}, expectError: true},
+		{name: "Unknown field", expressions: []string{"Bogus=1"}, expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseWhereClauses(tc.expressions)
+			if tc.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessEventsResources(t *testing.T) {
+	events := []ctypes.Event{
+		newLookupEvent(`{"eventID": "put", "eventName": "PutObject"}`, "alice",
+			ctypes.Resource{ResourceType: aws.String("AWS::S3::Bucket"), ResourceName: aws.String("reports")},
+			ctypes.Resource{ResourceType: aws.String("AWS::S3::Object"), ResourceName: aws.String("arn:aws:s3:::reports/q1.csv")},
+		),
+		newLookupEvent(`{"eventID": "run", "eventName": "RunInstances"}`, "bob",
+			ctypes.Resource{ResourceType: aws.String("AWS::EC2::Instance"), ResourceName: aws.String("i-0123456789")},
+		),
+		newLookupEvent(`{"eventID": "record", "eventName": "Decrypt", "resources": [{"ARN": "arn:aws:kms:us-east-1:111122223333:key/abc", "type": "AWS::KMS::Key"}]}`, ""),
+	}
+
+	testCases := []struct {
+		name     string
+		input    types.CloudTrailCliInput
+		expected [][]string
+	}{
+		{
+			name:  "Resources column",
+			input: types.CloudTrailCliInput{Columns: []string{"EventId", "Resources"}},
+			expected: [][]string{
+				{"put", "AWS::S3::Bucket reports\nAWS::S3::Object arn:aws:s3:::reports/q1.csv"},
+				{"run", "AWS::EC2::Instance i-0123456789"},
+				{"record", "AWS::KMS::Key arn:aws:kms:us-east-1:111122223333:key/abc"},
+			},
+		},
+		{
+			name:     "Filter on any resource value",
+			input:    types.CloudTrailCliInput{Columns: []string{"EventId"}, Where: []string{"ResourceType=AWS::S3::Object"}},
+			expected: [][]string{{"put"}},
+		},
+		{
+			name:     "Filter on lookup username with wildcard",
+			input:    types.CloudTrailCliInput{Columns: []string{"EventId", "LookupUsername"}, Where: []string{"LookupUsername=B*"}},
+			expected: [][]string{{"run", "bob"}},
+		},
+		{
+			name:     "Negated filters are combined",
+			input:    types.CloudTrailCliInput{Columns: []string{"EventId"}, Where: []string{"ResourceName!=reports", "EventName!=Decrypt"}},
+			expected: [][]string{{"run"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			rows := processEvents(events, tc.input)
+			if len(rows) != len(tc.expected) {
+				t.Fatalf("Expected %d rows, got %d: %v", len(tc.expected), len(rows), rows)
+			}
+			for idx, row := range rows {
+				for col, cell := range row {
+					if cell != tc.expected[idx][col] {
+						t.Errorf("Row %d column %d: expected %q, got %q", idx, col, tc.expected[idx][col], cell)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestClientFields(t *testing.T) {
+	events := []ctypes.Event{
+		newLookupEvent(`{"eventID": "cli", "userAgent": "aws-cli/2.15.30 Python/3.11.8 Darwin/23.4.0 exe/x86_64 prompt/off command/s3.ls"}`, ""),
+		newLookupEvent(`{"eventID": "tf", "userAgent": "APN/1.0 HashiCorp/1.0 Terraform/1.7.5 (+https://www.terraform.io) terraform-provider-aws/5.42.0 aws-sdk-go-v2/1.26.0 os/linux"}`, ""),
+		newLookupEvent(`{"eventID": "old-cli", "userAgent": "aws-cli/2.13.25 Python/3.11.5 Linux/6.1.0-13-amd64 exe/x86_64.debian.12"}`, ""),
+		newLookupEvent(`{"eventID": "eks", "userAgent": "eks.amazonaws.com"}`, ""),
+	}
+
+	testCases := []struct {
+		name     string
+		input    types.CloudTrailCliInput
+		expected [][]string
+	}{
+		{
+			name:  "Client columns",
+			input: types.CloudTrailCliInput{Columns: []string{"EventId", "Client", "ClientFamily"}},
+			expected: [][]string{
+				{"cli", "AWS CLI v2 2.15.30", "AWS CLI v2"},
+				{"tf", "Terraform 1.7.5", "Terraform"},
+				{"old-cli", "AWS CLI v2 2.13.25", "AWS CLI v2"},
+				{"eks", "AWS service internal", "AWS service internal"},
+			},
+		},
+		{
+			name:     "Filter on client version",
+			input:    types.CloudTrailCliInput{Columns: []string{"EventId"}, Where: []string{"Client=AWS CLI v2 2.13.*"}},
+			expected: [][]string{{"old-cli"}},
+		},
+		{
+			name:     "Filter on client family",
+			input:    types.CloudTrailCliInput{Columns: []string{"EventId"}, Where: []string{"ClientFamily!=AWS CLI*"}},
+			expected: [][]string{{"tf"}, {"eks"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			rows := processEvents(events, tc.input)
+			if len(rows) != len(tc.expected) {
+				t.Fatalf("Expected %d rows, got %d: %v", len(tc.expected), len(rows), rows)
+			}
+			for idx, row := range rows {
+				for col, cell := range row {
+					if cell != tc.expected[idx][col] {
+						t.Errorf("Row %d column %d: expected %q, got %q", idx, col, tc.expected[idx][col], cell)
+					}
+				}
+			}
+		})
+	}
+
+	header, rows := groupEvents(filterEvents(events, types.CloudTrailCliInput{}), types.CloudTrailCliInput{GroupBy: []string{"ClientFamily"}})
+	if len(header) != 2 || len(rows) != 3 || rows[0][0] != "AWS CLI v2" || rows[0][1] != 2 {
+		t.Errorf("unexpected grouping by client family: %v %v", header, rows)
+	}
+}
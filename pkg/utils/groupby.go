@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// resolveGroupBy returns the fields events are counted by
+func resolveGroupBy(names []string) ([]eventField, error) {
+	fields := make([]eventField, 0, len(names))
+	for _, name := range names {
+		field, ok := findEventField(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("invalid --group-by field %q, run 'columns' to list available fields", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// groupEvents counts events per distinct combination of the --group-by fields,
+// most frequent first. Multi-valued fields are grouped by their joined values.
+func groupEvents(events []*types.Event, config types.CloudTrailCliInput) (table.Row, []table.Row) {
+	fields, err := resolveGroupBy(config.GroupBy)
+	if err != nil {
+		return nil, nil
+	}
+
+	var header table.Row
+	for _, field := range fields {
+		header = append(header, field.Name)
+	}
+	header = append(header, "Count")
+
+	type group struct {
+		cells []string
+		count int
+	}
+	groups := make(map[string]*group)
+	for _, e := range events {
+		cells := buildCells(e, fields, config)
+		key := strings.Join(cells, "\x00")
+		if groups[key] == nil {
+			groups[key] = &group{cells: cells}
+		}
+		groups[key].count++
+	}
+
+	sorted := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].count != sorted[b].count {
+			return sorted[a].count > sorted[b].count
+		}
+		return strings.Join(sorted[a].cells, "\x00") < strings.Join(sorted[b].cells, "\x00")
+	})
+
+	rows := make([]table.Row, 0, len(sorted))
+	for _, g := range sorted {
+		row := make(table.Row, 0, len(header))
+		for _, cell := range g.cells {
+			row = append(row, cell)
+		}
+		rows = append(rows, append(row, g.count))
+	}
+	return header, rows
+}
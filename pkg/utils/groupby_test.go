@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/geoip"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func openTestGeoIP(t *testing.T) *geoip.Resolver {
+	t.Helper()
+	testdata := filepath.Join("..", "geoip", "testdata")
+	r, err := geoip.Open(filepath.Join(testdata, "GeoLite2-City-Test.mmdb"), filepath.Join(testdata, "GeoLite2-ASN-Test.mmdb"))
+	if err != nil {
+		t.Fatalf("failed to open GeoIP databases: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func geoEvents() []ctypes.Event {
+	return []ctypes.Event{
+		newLookupEvent(`{"eventID": "1", "eventName": "GetObject", "sourceIPAddress": "203.0.113.10"}`, ""),
+		newLookupEvent(`{"eventID": "2", "eventName": "PutObject", "sourceIPAddress": "203.0.113.11"}`, ""),
+		newLookupEvent(`{"eventID": "3", "eventName": "GetObject", "sourceIPAddress": "2001:db8::1"}`, ""),
+		newLookupEvent(`{"eventID": "4", "eventName": "AssumeRole", "sourceIPAddress": "eks.amazonaws.com"}`, ""),
+		newLookupEvent(`{"eventID": "5", "eventName": "GetObject", "sourceIPAddress": "203.0.113.12"}`, ""),
+	}
+}
+
+func TestGeoIPFields(t *testing.T) {
+	config := types.CloudTrailCliInput{
+		GeoIP:   openTestGeoIP(t),
+		Columns: []string{"SourceIPAddress", "Country", "City", "ASN", "ASOrganization"},
+	}
+
+	expected := []table.Row{
+		{"203.0.113.10", "JP", "Tokyo", "AS64496", "Example Networks"},
+		{"203.0.113.11", "JP", "Tokyo", "AS64496", "Example Networks"},
+		{"2001:db8::1", "US", "Seattle", "AS64511", "Example IPv6 Transit"},
+		{"eks.amazonaws.com", "", "", "", ""},
+		{"203.0.113.12", "JP", "Tokyo", "AS64496", "Example Networks"},
+	}
+	if rows := processEvents(geoEvents(), config); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v, got %v", expected, rows)
+	}
+
+	// Without databases the fields are empty
+	config.GeoIP = nil
+	if rows := processEvents(geoEvents()[:1], config); !reflect.DeepEqual(rows, []table.Row{{"203.0.113.10", "", "", "", ""}}) {
+		t.Errorf("expected empty GeoIP fields, got %v", rows)
+	}
+}
+
+func TestGroupEvents(t *testing.T) {
+	r := openTestGeoIP(t)
+
+	testCases := []struct {
+		name           string
+		config         types.CloudTrailCliInput
+		expectedHeader table.Row
+		expectedRows   []table.Row
+	}{
+		{
+			name:           "Single field",
+			config:         types.CloudTrailCliInput{GeoIP: r, GroupBy: []string{"country"}},
+			expectedHeader: table.Row{"Country", "Count"},
+			expectedRows:   []table.Row{{"JP", 3}, {"", 1}, {"US", 1}},
+		},
+		{
+			name:           "Several fields",
+			config:         types.CloudTrailCliInput{GeoIP: r, GroupBy: []string{"ASOrganization", "EventName"}},
+			expectedHeader: table.Row{"ASOrganization", "EventName", "Count"},
+			expectedRows: []table.Row{
+				{"Example Networks", "GetObject", 2},
+				{"", "AssumeRole", 1},
+				{"Example IPv6 Transit", "GetObject", 1},
+				{"Example Networks", "PutObject", 1},
+			},
+		},
+		{
+			name:           "Filtered events",
+			config:         types.CloudTrailCliInput{GeoIP: r, GroupBy: []string{"City"}, Where: []string{"Country!=JP"}},
+			expectedHeader: table.Row{"City", "Count"},
+			expectedRows:   []table.Row{{"", 1}, {"Seattle", 1}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			header, rows := groupEvents(filterEvents(geoEvents(), tc.config), tc.config)
+			if !reflect.DeepEqual(header, tc.expectedHeader) {
+				t.Errorf("expected header %v, got %v", tc.expectedHeader, header)
+			}
+			if !reflect.DeepEqual(rows, tc.expectedRows) {
+				t.Errorf("expected rows %v, got %v", tc.expectedRows, rows)
+			}
+		})
+	}
+}
@@ -6,15 +6,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
 	"github.com/guessi/cloudtrail-cli/pkg/constants"
 	"github.com/guessi/cloudtrail-cli/pkg/types"
 	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/jedib0t/go-pretty/v6/text"
 )
 
 // parseCloudTrailEvent safely parses CloudTrail event JSON with size validation
@@ -57,12 +58,58 @@ func validateInput(i types.CloudTrailCliInput) error {
 	if i.MaxResults > constants.MaxCloudTrailResults {
 		return fmt.Errorf("--max-results cannot exceed %d", constants.MaxCloudTrailResults)
 	}
+	if i.Output != "" && !containsString(constants.OutputFormats, i.Output) {
+		return fmt.Errorf("invalid --output %q, must be one of: %s", i.Output, strings.Join(constants.OutputFormats, ", "))
+	}
+	if i.IdentityFormat != "" && !containsString(constants.IdentityFormats, i.IdentityFormat) {
+		return fmt.Errorf("invalid --identity-format %q, must be one of: %s", i.IdentityFormat, strings.Join(constants.IdentityFormats, ", "))
+	}
+	if i.PivotOn != "" {
+		if !containsString(constants.PivotKeys, i.PivotOn) {
+			return fmt.Errorf("invalid --pivot-on %q, must be one of: %s", i.PivotOn, strings.Join(constants.PivotKeys, ", "))
+		}
+		if i.PivotWindow <= 0 {
+			return fmt.Errorf("--pivot-window must be a positive duration")
+		}
+		if i.Insights {
+			return fmt.Errorf("--pivot-on is not supported with --insights")
+		}
+	}
+	if _, err := resolveColumns(i.Columns); err != nil {
+		return err
+	}
+	if _, err := parseWhereClauses(i.Where); err != nil {
+		return err
+	}
+	if _, err := parseSourceIPFilter(i.SourceIPs); err != nil {
+		return err
+	}
+	if i.ExcludeKnownIPs && !i.IPTags.HasLabels() {
+		return fmt.Errorf("--exclude-known-ips requires --ip-labels")
+	}
+	if len(i.GroupBy) > 0 {
+		if i.Insights {
+			return fmt.Errorf("--group-by is not supported with --insights")
+		}
+		if _, err := resolveGroupBy(i.GroupBy); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// processEvents processes CloudTrail events and returns table rows
-func processEvents(events []ctypes.Event, config types.CloudTrailCliInput) []table.Row {
-	var rows []table.Row
+// filterEvents parses CloudTrail events and applies the client-side filters
+func filterEvents(events []ctypes.Event, config types.CloudTrailCliInput) []*types.Event {
+	clauses, err := parseWhereClauses(config.Where)
+	if err != nil {
+		return nil
+	}
+	sourceIPs, err := parseSourceIPFilter(config.SourceIPs)
+	if err != nil {
+		return nil
+	}
+
+	var filtered []*types.Event
 
 	for _, event := range events {
 		cloudTrailEvent, err := parseCloudTrailEvent(event)
@@ -71,30 +118,67 @@ func processEvents(events []ctypes.Event, config types.CloudTrailCliInput) []tab
 		}
 
 		// Apply error-only filter if requested
-		if config.ErrorOnly && cloudTrailEvent.ErrorCode == "" {
+		if config.ErrorOnly && !hasError(cloudTrailEvent) {
 			continue
 		}
 
-		username := getDisplayUserName(cloudTrailEvent.UserIdentity)
-		rows = append(rows, table.Row{
-			cloudTrailEvent.EventId,
-			cloudTrailEvent.EventName,
-			cloudTrailEvent.EventTime,
-			truncateString(config.TruncateUserName, username, constants.DefaultTruncateLength),
-			cloudTrailEvent.EventSource,
-			truncateString(config.TruncateUserAgent, cloudTrailEvent.UserAgent, constants.DefaultTruncateLength),
-			cloudTrailEvent.SourceIPAddress,
-			cloudTrailEvent.UserIdentity.AccessKeyId,
-			cloudTrailEvent.ErrorCode,
-			cloudTrailEvent.ReadOnly,
-		})
+		if !sourceIPs.matches(cloudTrailEvent.SourceIPAddress) {
+			continue
+		}
+
+		// Hide the traffic of our own infrastructure
+		if config.ExcludeKnownIPs && config.IPTags.Known(cloudTrailEvent.SourceIPAddress) {
+			continue
+		}
+
+		e := newEvent(event, cloudTrailEvent)
+		if !matchesAll(clauses, e, config) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+
+	return filtered
+}
+
+// buildCells renders the selected columns of an event
+func buildCells(e *types.Event, columns []eventField, config types.CloudTrailCliInput) []string {
+	cells := make([]string, 0, len(columns))
+	for _, column := range columns {
+		value := config.Redactor.Text(annotateAccounts(config, strings.Join(column.Values(e, config), "\n")))
+		switch column.Name {
+		case "Username":
+			value = truncateString(config.TruncateUserName, value, TruncateLength(config.UserNameLength))
+		case "UserAgent":
+			value = truncateString(config.TruncateUserAgent, value, TruncateLength(config.UserAgentLength))
+		}
+		cells = append(cells, value)
+	}
+	return cells
+}
+
+// processEvents processes CloudTrail events and returns table rows
+func processEvents(events []ctypes.Event, config types.CloudTrailCliInput) []table.Row {
+	columns, err := resolveColumns(config.Columns)
+	if err != nil {
+		return nil
+	}
+
+	var rows []table.Row
+
+	for _, e := range filterEvents(events, config) {
+		row := make(table.Row, 0, len(columns))
+		for _, cell := range buildCells(e, columns, config) {
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
 	}
 
 	return rows
 }
 
 // createCloudTrailClient creates and configures AWS CloudTrail client
-func createCloudTrailClient(ctx context.Context, region, profile string) (*cloudtrail.Client, error) {
+func createCloudTrailClient(ctx context.Context, region, profile, endpointUrl string) (*cloudtrail.Client, error) {
 	cfg, err := config.LoadDefaultConfig(
 		ctx,
 		config.WithRegion(region),
@@ -104,24 +188,20 @@ func createCloudTrailClient(ctx context.Context, region, profile string) (*cloud
 		return nil, fmt.Errorf("unable to load AWS configuration. Please check your credentials and region settings")
 	}
 
-	return cloudtrail.NewFromConfig(cfg), nil
+	return cloudtrail.NewFromConfig(cfg, func(o *cloudtrail.Options) {
+		if endpointUrl != "" {
+			o.BaseEndpoint = aws.String(endpointUrl)
+		}
+	}), nil
 }
 
-// renderTable creates and renders the output table
-func renderTable(rows []table.Row) {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{
-		"EventId", "EventName", "EventTime", "Username", "EventSource",
-		"UserAgent", "SourceIPAddress", "AccessKeyId", "ErrorCode", "ReadOnly",
-	})
-
-	for _, row := range rows {
-		t.AppendRow(row)
+// columnHeader returns the table header for the selected columns
+func columnHeader(config types.CloudTrailCliInput) table.Row {
+	var header table.Row
+	for _, name := range columnNames(config) {
+		header = append(header, name)
 	}
-
-	t.Style().Format.Header = text.FormatDefault
-	t.Render()
+	return header
 }
 
 // buildCloudTrailInput creates the CloudTrail API request input
@@ -130,17 +210,40 @@ func buildCloudTrailInput(i types.CloudTrailCliInput) (*cloudtrail.LookupEventsI
 	if err != nil {
 		return nil, err
 	}
-	return &cloudtrail.LookupEventsInput{
+	input := &cloudtrail.LookupEventsInput{
 		StartTime:        &i.StartTime,
 		EndTime:          &i.EndTime,
 		LookupAttributes: attrs,
 		MaxResults:       getBatchSize(i.MaxResults),
-	}, nil
+	}
+	if i.Insights {
+		input.EventCategory = ctypes.EventCategoryInsight
+	}
+	return input, nil
 }
 
 // LookupEventsFunc type for dependency injection
 type LookupEventsFunc func(ctx context.Context, svc *cloudtrail.Client, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error)
 
+// eventFetcher retrieves events for a prepared request with the client already bound
+type eventFetcher func(ctx context.Context, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error)
+
+// newEventFetcher binds a CloudTrail client to the given lookup function
+func newEventFetcher(svc *cloudtrail.Client, lookupFunc LookupEventsFunc) eventFetcher {
+	return func(ctx context.Context, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error) {
+		return lookupFunc(ctx, svc, input, maxResults)
+	}
+}
+
+// prepareTimeRange applies the default time range and validates its boundaries
+func prepareTimeRange(i *types.CloudTrailCliInput) error {
+	setDefaultTimeRange(i)
+	if i.StartTime.After(i.EndTime) {
+		return fmt.Errorf("start time cannot be after end time")
+	}
+	return nil
+}
+
 // eventsHandlerWithLookup allows injection of LookupEvents function for testing
 func eventsHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
 	// Validate input parameters
@@ -152,15 +255,14 @@ func eventsHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEvents
 	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
 	defer cancel()
 
-	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile)
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
 	if err != nil {
 		return err
 	}
 
 	// Configure time range and validate
-	setDefaultTimeRange(&i)
-	if i.StartTime.After(i.EndTime) {
-		return fmt.Errorf("start time cannot be after end time")
+	if err := prepareTimeRange(&i); err != nil {
+		return err
 	}
 
 	// Build CloudTrail API request
@@ -175,12 +277,35 @@ func eventsHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEvents
 		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
 	}
 
+	// Follow up with the events related to the results
+	if i.PivotOn != "" {
+		events, err = pivotEvents(ctx, newEventFetcher(svc, lookupFunc), i, events)
+		if err != nil {
+			return err
+		}
+		// Related events are displayed without the filters of the first query
+		i = pivotInput(i)
+	}
+
+	// Insights events have a dedicated layout
+	if i.Insights {
+		return renderRows(os.Stdout, i.Output, insightHeader, processInsights(events, i))
+	}
+
+	// Count events instead of listing them
+	if len(i.GroupBy) > 0 {
+		header, rows := groupEvents(filterEvents(events, i), i)
+		return renderRows(os.Stdout, i.Output, header, rows)
+	}
+
 	// Process and display events
 	rows := processEvents(events, i)
-	renderTable(rows)
-	return nil
+	return renderRows(os.Stdout, i.Output, columnHeader(i), rows)
 }
 
 func EventsHandler(i types.CloudTrailCliInput) error {
+	if i.EventDataStore != "" {
+		return lakeEventsHandler(i)
+	}
 	return eventsHandlerWithLookup(i, LookupEvents)
 }
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -8,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
 	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/iprange"
 	"github.com/guessi/cloudtrail-cli/pkg/types"
 )
 
@@ -45,6 +48,56 @@ func TestValidateInput(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"Valid identity format",
+			types.CloudTrailCliInput{
+				MaxResults:     10,
+				IdentityFormat: "role/session",
+			},
+			false,
+		},
+		{
+			"Invalid identity format",
+			types.CloudTrailCliInput{
+				MaxResults:     10,
+				IdentityFormat: "email",
+			},
+			true,
+		},
+		{
+			"Pivot on Insights events",
+			types.CloudTrailCliInput{
+				MaxResults:  10,
+				Insights:    true,
+				PivotOn:     "user",
+				PivotWindow: time.Hour,
+			},
+			true,
+		},
+		{
+			"Group by GeoIP fields",
+			types.CloudTrailCliInput{
+				MaxResults: 10,
+				GroupBy:    []string{"Country", "ASOrganization"},
+			},
+			false,
+		},
+		{
+			"Exclude known IPs without labels",
+			types.CloudTrailCliInput{
+				MaxResults:      10,
+				ExcludeKnownIPs: true,
+			},
+			true,
+		},
+		{
+			"Group by unknown field",
+			types.CloudTrailCliInput{
+				MaxResults: 10,
+				GroupBy:    []string{"Continent"},
+			},
+			true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -87,6 +140,18 @@ func TestBuildCloudTrailInput(t *testing.T) {
 	if result.MaxResults == nil || *result.MaxResults != int32(10) {
 		t.Errorf("MaxResults = %v, want 10", result.MaxResults)
 	}
+	if result.EventCategory != "" {
+		t.Errorf("EventCategory = %q, want none", result.EventCategory)
+	}
+
+	input.Insights = true
+	result, err = buildCloudTrailInput(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.EventCategory != ctypes.EventCategoryInsight {
+		t.Errorf("EventCategory = %q, want %q", result.EventCategory, ctypes.EventCategoryInsight)
+	}
 }
 
 func TestParseCloudTrailEvent(t *testing.T) {
@@ -138,3 +203,54 @@ func TestProcessEvents(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterEventsExcludeKnownIPs(t *testing.T) {
+	testdata := filepath.Join("..", "iprange", "testdata")
+	tagger, err := iprange.New(filepath.Join(testdata, "ip-ranges.json"), []string{filepath.Join(testdata, "labels.txt")})
+	if err != nil {
+		t.Fatalf("failed to load IP ranges: %v", err)
+	}
+
+	events := []ctypes.Event{
+		newLookupEvent(`{"eventID": "office", "sourceIPAddress": "198.51.100.7"}`, ""),
+		newLookupEvent(`{"eventID": "ci", "sourceIPAddress": "52.94.76.10"}`, ""),
+		newLookupEvent(`{"eventID": "ec2", "sourceIPAddress": "52.94.76.11"}`, ""),
+		newLookupEvent(`{"eventID": "service", "sourceIPAddress": "eks.amazonaws.com"}`, ""),
+		newLookupEvent(`{"eventID": "unknown", "sourceIPAddress": "203.0.113.10"}`, ""),
+	}
+
+	testCases := []struct {
+		name     string
+		input    types.CloudTrailCliInput
+		expected []string
+	}{
+		{
+			name:     "Known IPs kept by default",
+			input:    types.CloudTrailCliInput{IPTags: tagger},
+			expected: []string{"office", "ci", "ec2", "service", "unknown"},
+		},
+		{
+			name:     "Known IPs excluded",
+			input:    types.CloudTrailCliInput{IPTags: tagger, ExcludeKnownIPs: true},
+			expected: []string{"ec2", "service", "unknown"},
+		},
+		{
+			name:     "Filter on AWS service",
+			input:    types.CloudTrailCliInput{IPTags: tagger, ExcludeKnownIPs: true, Where: []string{"SourceIPService=EC2"}},
+			expected: []string{"ec2"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []string
+			for _, e := range filterEvents(events, tc.input) {
+				ids = append(ids, e.Record.EventId)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Reference:
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-insights-events-record-contents.html
+
+const apiErrorRateInsight = "ApiErrorRateInsight"
+
+// insightHeader is the table header of Insights events
+var insightHeader = table.Row{
+	"EventId", "EventTime", "State", "InsightType", "EventSource", "EventName", "ErrorCode",
+	"Baseline", "Insight", "Change", "Minutes", "TopContributors",
+}
+
+// hasError reports whether an event failed, or an Insights event is about errors
+func hasError(record *types.CloudTrailEvent) bool {
+	if record.ErrorCode != "" {
+		return true
+	}
+	return record.InsightDetails != nil && record.InsightDetails.InsightType == apiErrorRateInsight
+}
+
+// insightAverage returns the average the insight type is measured on: the
+// error count per minute for error rate insights, otherwise the call count
+func insightAverage(details *types.InsightDetails, statistic types.InsightStatistic) *float64 {
+	if details.InsightType == apiErrorRateInsight && statistic.ErrorCount != nil {
+		return statistic.ErrorCount
+	}
+	return statistic.Average
+}
+
+// formatFloat renders an optional statistic
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', 4, 64)
+}
+
+// formatChange renders how many times the insight average exceeds the baseline
+func formatChange(baseline, insight *float64) string {
+	if baseline == nil || insight == nil || *baseline == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1fx", *insight / *baseline)
+}
+
+// topContributors returns the largest contributor of each attribute during the insight
+func topContributors(attributions []types.InsightAttribution) []string {
+	var contributors []string
+	for _, attribution := range attributions {
+		var top *types.InsightAttributeValue
+		for n, value := range attribution.Insight {
+			if top == nil || (value.Average != nil && (top.Average == nil || *value.Average > *top.Average)) {
+				top = &attribution.Insight[n]
+			}
+		}
+		if top == nil {
+			continue
+		}
+		contributors = append(contributors, fmt.Sprintf("%s: %s", attribution.Attribute, top.Value))
+	}
+	return contributors
+}
+
+// buildInsightRow renders the insight details of an event
+func buildInsightRow(e *types.Event) table.Row {
+	details := e.Record.InsightDetails
+	statistics := details.InsightContext.Statistics
+	baseline := insightAverage(details, statistics.Baseline)
+	insight := insightAverage(details, statistics.Insight)
+
+	return table.Row{
+		e.Record.EventId,
+		e.Record.EventTime,
+		details.State,
+		details.InsightType,
+		details.EventSource,
+		details.EventName,
+		details.ErrorCode,
+		formatFloat(baseline),
+		formatFloat(insight),
+		formatChange(baseline, insight),
+		formatFloat(statistics.InsightDuration),
+		strings.Join(topContributors(details.InsightContext.Attributions), "\n"),
+	}
+}
+
+// processInsights processes Insights events and returns table rows
+func processInsights(events []ctypes.Event, config types.CloudTrailCliInput) []table.Row {
+	var rows []table.Row
+	for _, e := range filterEvents(events, config) {
+		if e.Record.InsightDetails == nil {
+			continue
+		}
+		rows = append(rows, buildInsightRow(e))
+	}
+	return displayRows(config, rows)
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+const callRateInsight = `{
+	"eventID": "aa11bb22-cc33-4d44-8e55-ff6677889900",
+	"eventTime": "2025-05-12T00:05:00Z",
+	"eventType": "AwsCloudTrailInsight",
+	"insightDetails": {
+		"state": "Start",
+		"eventSource": "ssm.amazonaws.com",
+		"eventName": "UpdateInstanceAssociationStatus",
+		"insightType": "ApiCallRateInsight",
+		"insightContext": {
+			"statistics": {
+				"baseline": {"average": 0.002},
+				"insight": {"average": 6},
+				"insightDuration": 1,
+				"baselineDuration": 11459
+			},
+			"attributions": [
+				{
+					"attribute": "userIdentityArn",
+					"insight": [
+						{"value": "arn:aws:sts::123456789012:assumed-role/Ops/i-0abc", "average": 1},
+						{"value": "arn:aws:sts::123456789012:assumed-role/Ops/i-0def", "average": 5}
+					]
+				},
+				{
+					"attribute": "errorCode",
+					"insight": [{"value": "null", "average": 6}]
+				}
+			]
+		}
+	},
+	"eventCategory": "Insight"
+}`
+
+const errorRateInsight = `{
+	"eventID": "bb22cc33-dd44-4e55-8f66-001122334455",
+	"eventTime": "2025-05-12T01:00:00Z",
+	"eventType": "AwsCloudTrailInsight",
+	"insightDetails": {
+		"state": "End",
+		"eventSource": "iam.amazonaws.com",
+		"eventName": "GetRole",
+		"insightType": "ApiErrorRateInsight",
+		"errorCode": "AccessDenied",
+		"insightContext": {
+			"statistics": {
+				"baseline": {"average": 0.1, "errorCount": 0.5},
+				"insight": {"average": 0.9, "errorCount": 20},
+				"insightDuration": 12
+			}
+		}
+	},
+	"eventCategory": "Insight"
+}`
+
+func TestProcessInsights(t *testing.T) {
+	events := []ctypes.Event{
+		{CloudTrailEvent: aws.String(callRateInsight)},
+		{CloudTrailEvent: aws.String(errorRateInsight)},
+		{CloudTrailEvent: aws.String(`{"eventID": "management-event", "eventName": "GetRole"}`)},
+	}
+
+	testCases := []struct {
+		name     string
+		config   types.CloudTrailCliInput
+		expected []table.Row
+	}{
+		{
+			name: "All Insights events",
+			expected: []table.Row{
+				{
+					"aa11bb22-cc33-4d44-8e55-ff6677889900", "2025-05-12T00:05:00Z", "Start", "ApiCallRateInsight",
+					"ssm.amazonaws.com", "UpdateInstanceAssociationStatus", "",
+					"0.002", "6", "3000.0x", "1",
+					"userIdentityArn: arn:aws:sts::123456789012:assumed-role/Ops/i-0def\nerrorCode: null",
+				},
+				{
+					"bb22cc33-dd44-4e55-8f66-001122334455", "2025-05-12T01:00:00Z", "End", "ApiErrorRateInsight",
+					"iam.amazonaws.com", "GetRole", "AccessDenied",
+					"0.5", "20", "40.0x", "12", "",
+				},
+			},
+		},
+		{
+			name:   "Error rate Insights only",
+			config: types.CloudTrailCliInput{ErrorOnly: true},
+			expected: []table.Row{
+				{
+					"bb22cc33-dd44-4e55-8f66-001122334455", "2025-05-12T01:00:00Z", "End", "ApiErrorRateInsight",
+					"iam.amazonaws.com", "GetRole", "AccessDenied",
+					"0.5", "20", "40.0x", "12", "",
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			rows := processInsights(events, tc.config)
+			if !reflect.DeepEqual(rows, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, rows)
+			}
+		})
+	}
+}
+
+func TestFormatChange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baseline *float64
+		insight  *float64
+		expected string
+	}{
+		{"Increase", aws.Float64(2), aws.Float64(5), "2.5x"},
+		{"No baseline activity", aws.Float64(0), aws.Float64(5), ""},
+		{"Missing statistic", nil, aws.Float64(5), ""},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatChange(tc.baseline, tc.insight); got != tc.expected {
+				t.Errorf("formatChange() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
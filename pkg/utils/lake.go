@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// lakeAPI is the subset of the CloudTrail client used to run Lake queries
+type lakeAPI interface {
+	StartQuery(ctx context.Context, params *cloudtrail.StartQueryInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.StartQueryOutput, error)
+	DescribeQuery(ctx context.Context, params *cloudtrail.DescribeQueryInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DescribeQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudtrail.GetQueryResultsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetQueryResultsOutput, error)
+	CancelQuery(ctx context.Context, params *cloudtrail.CancelQueryInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.CancelQueryOutput, error)
+}
+
+// reportQueryProgress prints the statistics of a running query
+func reportQueryProgress(w io.Writer, queryId string, out *cloudtrail.DescribeQueryOutput) {
+	stats := out.QueryStatistics
+	if stats == nil {
+		fmt.Fprintf(w, "Query %s %s\n", queryId, out.QueryStatus)
+		return
+	}
+	fmt.Fprintf(w, "Query %s %s: %d events scanned (%d bytes), %d matched, %s elapsed\n",
+		queryId,
+		out.QueryStatus,
+		aws.ToInt64(stats.EventsScanned),
+		aws.ToInt64(stats.BytesScanned),
+		aws.ToInt64(stats.EventsMatched),
+		time.Duration(aws.ToInt32(stats.ExecutionTimeInMillis))*time.Millisecond,
+	)
+}
+
+// waitForQuery polls a query until it reaches a final status
+func waitForQuery(ctx context.Context, api lakeAPI, queryId string, interval time.Duration, progress io.Writer) error {
+	for {
+		out, err := api.DescribeQuery(ctx, &cloudtrail.DescribeQueryInput{QueryId: aws.String(queryId)})
+		if err != nil {
+			return fmt.Errorf("unable to describe query %s: %w", queryId, err)
+		}
+		reportQueryProgress(progress, queryId, out)
+
+		switch out.QueryStatus {
+		case ctypes.QueryStatusFinished:
+			return nil
+		case ctypes.QueryStatusFailed, ctypes.QueryStatusCancelled, ctypes.QueryStatusTimedOut:
+			if message := aws.ToString(out.ErrorMessage); message != "" {
+				return fmt.Errorf("query %s %s: %s", queryId, out.QueryStatus, message)
+			}
+			return fmt.Errorf("query %s %s", queryId, out.QueryStatus)
+		}
+
+		select {
+		case <-ctx.Done():
+			// Do not leave the query running, it is billed by the data scanned
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_, _ = api.CancelQuery(cancelCtx, &cloudtrail.CancelQueryInput{QueryId: aws.String(queryId)})
+			return fmt.Errorf("query %s did not finish in time: %w", queryId, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// fetchQueryResults pages the results of a finished query. Each result row is a
+// list of single-entry column maps. Rows may leave out columns, such as those
+// that are null, so the header lists every column in the order it is first
+// seen and the rows lacking one get an empty value.
+func fetchQueryResults(ctx context.Context, api lakeAPI, queryId string, maxResults int) (table.Row, []table.Row, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	var records []map[string]string
+
+	input := &cloudtrail.GetQueryResultsInput{QueryId: aws.String(queryId)}
+	for len(records) < maxResults {
+		out, err := api.GetQueryResults(ctx, input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to retrieve results of query %s: %w", queryId, err)
+		}
+
+		for _, resultRow := range out.QueryResultRows {
+			values := make(map[string]string, len(resultRow))
+			for _, cell := range resultRow {
+				for column, value := range cell {
+					if !seen[column] {
+						seen[column] = true
+						columns = append(columns, column)
+					}
+					values[column] = value
+				}
+			}
+			records = append(records, values)
+
+			if len(records) >= maxResults {
+				break
+			}
+		}
+
+		if aws.ToString(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	header := make(table.Row, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column)
+	}
+	rows := make([]table.Row, 0, len(records))
+	for _, values := range records {
+		row := make(table.Row, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, values[column])
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// runLakeQuery submits a SQL statement and returns its results
+func runLakeQuery(ctx context.Context, api lakeAPI, statement string, interval time.Duration, maxResults int, progress io.Writer) (table.Row, []table.Row, error) {
+	started, err := api.StartQuery(ctx, &cloudtrail.StartQueryInput{QueryStatement: aws.String(statement)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to start query: %w", err)
+	}
+	queryId := aws.ToString(started.QueryId)
+
+	if err := waitForQuery(ctx, api, queryId, interval, progress); err != nil {
+		return nil, nil, err
+	}
+	return fetchQueryResults(ctx, api, queryId, maxResults)
+}
+
+// lakeQueryHandlerWithClient allows injection of the CloudTrail client for testing
+func lakeQueryHandlerWithClient(i types.CloudTrailCliInput, api lakeAPI, w io.Writer) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+	if i.LakeQuery == "" {
+		return fmt.Errorf("a SQL statement is required, e.g. 'lake query \"SELECT ...\"'")
+	}
+	if i.PollInterval <= 0 {
+		return fmt.Errorf("--poll-interval must be a positive duration")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	header, rows, err := runLakeQuery(ctx, api, i.LakeQuery, i.PollInterval, i.MaxResults, os.Stderr)
+	if err != nil {
+		return err
+	}
+	return renderRows(w, i.Output, header, displayRows(i, rows))
+}
+
+// LakeQueryHandler runs a SQL query against CloudTrail Lake event data stores
+func LakeQueryHandler(i types.CloudTrailCliInput) error {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+	return lakeQueryHandlerWithClient(i, svc, os.Stdout)
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// fakeLake is a local CloudTrail endpoint serving the Lake query operations
+type fakeLake struct {
+	mu         sync.Mutex
+	statement  string
+	describes  int
+	statuses   []string
+	pages      []map[string]interface{}
+	cancelled  bool
+	operations []string
+}
+
+func (f *fakeLake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "CloudTrail_20131101.")
+	f.operations = append(f.operations, operation)
+
+	var response interface{}
+	switch operation {
+	case "StartQuery":
+		f.statement, _ = body["QueryStatement"].(string)
+		response = map[string]interface{}{"QueryId": "query-1"}
+	case "DescribeQuery":
+		status := f.statuses[min(f.describes, len(f.statuses)-1)]
+		f.describes++
+		response = map[string]interface{}{
+			"QueryId":      "query-1",
+			"QueryStatus":  status,
+			"ErrorMessage": "syntax error",
+			"QueryStatistics": map[string]interface{}{
+				"EventsScanned":         1200,
+				"EventsMatched":         3,
+				"BytesScanned":          4096,
+				"ExecutionTimeInMillis": 150,
+			},
+		}
+	case "GetQueryResults":
+		page := 0
+		if token, ok := body["NextToken"].(string); ok && token == "page-2" {
+			page = 1
+		}
+		response = f.pages[page]
+	case "CancelQuery":
+		f.cancelled = true
+		response = map[string]interface{}{"QueryId": "query-1", "QueryStatus": "CANCELLED"}
+	default:
+		http.Error(w, "unknown operation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// newFakeLakeClient points a CloudTrail client at the fake endpoint
+func newFakeLakeClient(t *testing.T, fake *fakeLake) lakeAPI {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAFAKE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	svc, err := createCloudTrailClient(context.Background(), "us-east-1", "", server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return svc
+}
+
+func resultPage(nextToken string, rows ...[]map[string]string) map[string]interface{} {
+	page := map[string]interface{}{"QueryStatus": "FINISHED", "QueryResultRows": rows}
+	if nextToken != "" {
+		page["NextToken"] = nextToken
+	}
+	return page
+}
+
+func TestLakeQueryHandler(t *testing.T) {
+	fake := &fakeLake{
+		statuses: []string{"QUEUED", "RUNNING", "FINISHED"},
+		pages: []map[string]interface{}{
+			resultPage("page-2",
+				[]map[string]string{{"eventName": "PutObject"}, {"total": "12"}},
+				[]map[string]string{{"eventName": "GetObject"}, {"total": "7"}},
+			),
+			resultPage("",
+				[]map[string]string{{"eventName": "DeleteObject"}, {"total": "1"}},
+			),
+		},
+	}
+	api := newFakeLakeClient(t, fake)
+
+	input := types.CloudTrailCliInput{
+		LakeQuery:    "SELECT eventName, COUNT(*) AS total FROM eds GROUP BY eventName",
+		PollInterval: time.Millisecond,
+		MaxResults:   100,
+		Output:       "csv",
+	}
+
+	var b bytes.Buffer
+	if err := lakeQueryHandlerWithClient(input, api, &b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "eventName,total\nPutObject,12\nGetObject,7\nDeleteObject,1\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected output:\n%s", b.String())
+	}
+	if fake.statement != input.LakeQuery {
+		t.Errorf("Expected statement %q to be submitted, got %q", input.LakeQuery, fake.statement)
+	}
+	if fake.describes != 3 {
+		t.Errorf("Expected 3 status checks, got %d", fake.describes)
+	}
+}
+
+func TestLakeQueryHandlerSparseColumns(t *testing.T) {
+	// Lake leaves null columns out of a row, so later rows and pages may
+	// bring columns the first row lacks
+	fake := &fakeLake{
+		statuses: []string{"FINISHED"},
+		pages: []map[string]interface{}{
+			resultPage("page-2",
+				[]map[string]string{{"eventName": "PutObject"}},
+				[]map[string]string{{"eventName": "GetObject"}, {"errorCode": "AccessDenied"}},
+			),
+			resultPage("",
+				[]map[string]string{{"userName": "alice"}, {"eventName": "DeleteObject"}},
+			),
+		},
+	}
+	api := newFakeLakeClient(t, fake)
+
+	input := types.CloudTrailCliInput{
+		LakeQuery:    "SELECT eventName, errorCode, userIdentity.userName AS userName FROM eds",
+		PollInterval: time.Millisecond,
+		MaxResults:   100,
+		Output:       "csv",
+	}
+
+	var b bytes.Buffer
+	if err := lakeQueryHandlerWithClient(input, api, &b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "eventName,errorCode,userName\nPutObject,,\nGetObject,AccessDenied,\nDeleteObject,,alice\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected output:\n%s", b.String())
+	}
+}
+
+func TestLakeQueryHandlerMaxResults(t *testing.T) {
+	fake := &fakeLake{
+		statuses: []string{"FINISHED"},
+		pages: []map[string]interface{}{
+			resultPage("page-2",
+				[]map[string]string{{"eventName": "PutObject"}},
+				[]map[string]string{{"eventName": "GetObject"}},
+			),
+		},
+	}
+	api := newFakeLakeClient(t, fake)
+
+	input := types.CloudTrailCliInput{LakeQuery: "SELECT eventName FROM eds", PollInterval: time.Millisecond, MaxResults: 1, Output: "json"}
+
+	var b bytes.Buffer
+	if err := lakeQueryHandlerWithClient(input, api, &b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var rows []map[string]string
+	if err := json.Unmarshal(b.Bytes(), &rows); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["eventName"] != "PutObject" {
+		t.Errorf("Expected only the first row, got %v", rows)
+	}
+	pages := 0
+	for _, operation := range fake.operations {
+		if operation == "GetQueryResults" {
+			pages++
+		}
+	}
+	if pages != 1 {
+		t.Errorf("Expected a single results page, got %v", fake.operations)
+	}
+}
+
+func TestLakeQueryHandlerFailedQuery(t *testing.T) {
+	fake := &fakeLake{statuses: []string{"RUNNING", "FAILED"}}
+	api := newFakeLakeClient(t, fake)
+
+	input := types.CloudTrailCliInput{LakeQuery: "SELEKT", PollInterval: time.Millisecond, MaxResults: 10}
+
+	err := lakeQueryHandlerWithClient(input, api, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("Expected the query error message, got %v", err)
+	}
+}
+
+func TestLakeQueryHandlerValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input types.CloudTrailCliInput
+	}{
+		{name: "Missing statement", input: types.CloudTrailCliInput{PollInterval: time.Second, MaxResults: 10}},
+		{name: "Invalid poll interval", input: types.CloudTrailCliInput{LakeQuery: "SELECT 1", MaxResults: 10}},
+		{name: "Invalid output", input: types.CloudTrailCliInput{LakeQuery: "SELECT 1", PollInterval: time.Second, MaxResults: 10, Output: "xml"}},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if err := lakeQueryHandlerWithClient(tc.input, nil, &bytes.Buffer{}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
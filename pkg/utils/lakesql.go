@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// References:
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/query-limitations.html
+// - https://docs.aws.amazon.com/awscloudtrail/latest/userguide/query-supported-functions.html
+
+// lakeColumns maps fields to the columns of a CloudTrail Lake event data store.
+// Fields without a column cannot be selected or filtered on in Lake queries.
+var lakeColumns = map[string]string{
+	"EventId":                "eventID",
+	"EventName":              "eventName",
+	"EventTime":              "eventTime",
+	"IdentityType":           "userIdentity.type",
+	"EventSource":            "eventSource",
+	"UserAgent":              "userAgent",
+	"SourceIPAddress":        "sourceIPAddress",
+	"AccessKeyId":            "userIdentity.accessKeyId",
+	"ErrorCode":              "errorCode",
+	"ReadOnly":               "readOnly",
+	"ErrorMessage":           "errorMessage",
+	"AwsRegion":              "awsRegion",
+	"EventType":              "eventType",
+	"EventCategory":          "eventCategory",
+	"EventVersion":           "eventVersion",
+	"ManagementEvent":        "managementEvent",
+	"RecipientAccountId":     "recipientAccountId",
+	"RequestId":              "requestID",
+	"SharedEventId":          "sharedEventID",
+	"VpcEndpointId":          "vpcEndpointId",
+	"UserIdentityArn":        "userIdentity.arn",
+	"AccountId":              "userIdentity.accountId",
+	"PrincipalId":            "userIdentity.principalId",
+	"SessionIssuerUserName":  "userIdentity.sessionContext.sessionIssuer.userName",
+	"SessionIssuerArn":       "userIdentity.sessionContext.sessionIssuer.arn",
+	"SessionIssuerAccountId": "userIdentity.sessionContext.sessionIssuer.accountId",
+	"MfaAuthenticated":       "userIdentity.sessionContext.attributes.mfaAuthenticated",
+	"InvokedBy":              "userIdentity.invokedBy",
+	"CredentialId":           "userIdentity.credentialId",
+	"TLSVersion":             "tlsDetails.tlsVersion",
+	"LoginResult":            "element_at(responseElements, 'ConsoleLogin')",
+	"MFAUsed":                "element_at(additionalEventData, 'MFAUsed')",
+}
+
+// lakeArnName is the last path segment of the caller ARN, like arnResourceName
+const lakeArnName = "regexp_extract(userIdentity.arn, '[^/:]+$')"
+
+// lakeUsername returns the expression of the Username field, which mirrors
+// formatIdentityName: the session name of assumed roles, the invoking service
+// of AWS services and so on. IAM Identity Center callers are named by user
+// name or principal ID, onBehalfOf not being a Lake column.
+func lakeUsername(identityFormat string) string {
+	session := "NULLIF(split_part(userIdentity.arn, '/', 3), '')"
+	assumedRole := session
+	switch identityFormat {
+	case "role":
+		assumedRole = fmt.Sprintf("CASE WHEN %s IS NOT NULL THEN split_part(userIdentity.arn, '/', 2) END", session)
+	case "role/session":
+		assumedRole = fmt.Sprintf("CASE WHEN %s IS NOT NULL THEN split_part(userIdentity.arn, '/', 2) || '/' || %s END", session, session)
+	}
+
+	cases := []string{
+		"WHEN userIdentity.type = 'Root' THEN COALESCE('root@' || userIdentity.accountId, 'root')",
+		"WHEN userIdentity.type = 'IAMUser' THEN COALESCE(userIdentity.userName, " + lakeArnName + ")",
+		"WHEN userIdentity.type = 'AssumedRole' THEN COALESCE(" + assumedRole + ", userIdentity.arn)",
+		"WHEN userIdentity.type = 'Role' THEN " + lakeArnName,
+		"WHEN userIdentity.type = 'FederatedUser' THEN COALESCE(" + lakeArnName + ", NULLIF(split_part(userIdentity.principalId, ':', 2), ''))",
+		"WHEN userIdentity.type = 'AWSService' THEN userIdentity.invokedBy",
+		"WHEN userIdentity.type = 'AWSAccount' THEN COALESCE(userIdentity.accountId, userIdentity.principalId) || COALESCE(' (' || userIdentity.invokedBy || ')', '')",
+		"WHEN userIdentity.type IN ('IdentityCenterUser', 'WebIdentityUser', 'SAMLUser', 'Directory', 'Unknown') THEN COALESCE(userIdentity.userName, userIdentity.principalId)",
+		"ELSE COALESCE(userIdentity.userName, userIdentity.arn)",
+	}
+	name := "CASE " + strings.Join(cases, " ") + " END"
+	if identityFormat == "arn" {
+		name = "COALESCE(NULLIF(userIdentity.arn, ''), " + name + ")"
+	}
+	return "COALESCE(NULLIF(" + name + ", ''), '-')"
+}
+
+// lakeColumn returns the Lake expression of a field
+func lakeColumn(name, identityFormat string) (string, bool) {
+	if name == "Username" {
+		return lakeUsername(identityFormat), true
+	}
+	column, ok := lakeColumns[name]
+	return column, ok
+}
+
+// lakeTimeLayout is the timestamp format of Lake queries
+const lakeTimeLayout = "2006-01-02 15:04:05"
+
+// eventDataStoreId accepts an event data store ID or ARN
+var eventDataStoreId = regexp.MustCompile(`^(?:arn:[\w-]+:cloudtrail:[\w-]+:\d{12}:eventdatastore/)?([A-Za-z0-9-]+)$`)
+
+// sqlString quotes a string literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlLikePattern converts a "*" wildcard pattern into a LIKE pattern escaped with backslashes
+func sqlLikePattern(pattern string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(pattern)
+	return strings.ReplaceAll(escaped, "*", "%")
+}
+
+// whereCondition translates a --where clause. Matching is case-insensitive like
+// client-side filtering, and an empty value matches missing columns.
+func whereCondition(clause whereClause, identityFormat string) (string, error) {
+	column, ok := lakeColumn(clause.field.Name, identityFormat)
+	if !ok {
+		return "", fmt.Errorf("--where on %s cannot be translated to a Lake query", clause.field.Name)
+	}
+	if clause.field.Name == "ReadOnly" || clause.field.Name == "ManagementEvent" {
+		column = "CAST(" + column + " AS varchar)"
+	}
+
+	var condition string
+	switch {
+	case clause.value == "":
+		condition = fmt.Sprintf("(%s IS NULL OR %s = '')", column, column)
+	case strings.Contains(clause.value, "*"):
+		condition = fmt.Sprintf(`lower(%s) LIKE %s ESCAPE '\'`, column, sqlString(strings.ToLower(sqlLikePattern(clause.value))))
+	default:
+		condition = fmt.Sprintf("lower(%s) = %s", column, sqlString(strings.ToLower(clause.value)))
+	}
+
+	if !clause.negate {
+		return condition, nil
+	}
+	if clause.value == "" {
+		return "NOT " + condition, nil
+	}
+	return fmt.Sprintf("(%s IS NULL OR NOT %s)", column, condition), nil
+}
+
+// lakeSelectColumns returns the select list. Default columns without a Lake
+// column are left out, explicitly requested ones are an error.
+func lakeSelectColumns(i types.CloudTrailCliInput) ([]string, error) {
+	columns, err := resolveColumns(i.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, column := range columns {
+		expression, ok := lakeColumn(column.Name, i.IdentityFormat)
+		if !ok {
+			if len(i.Columns) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("column %s is not available in Lake queries", column.Name)
+		}
+		if column.Name == "EventTime" {
+			expression = "CAST(eventTime AS varchar)"
+		}
+		selected = append(selected, fmt.Sprintf("%s AS %s", expression, column.Name))
+	}
+	return selected, nil
+}
+
+// buildLakeQuery compiles the lookup flags into a CloudTrail Lake statement.
+// Unlike LookupEvents, any number of filters can be combined.
+func buildLakeQuery(i types.CloudTrailCliInput) (string, error) {
+	m := eventDataStoreId.FindStringSubmatch(i.EventDataStore)
+	if m == nil {
+		return "", fmt.Errorf("invalid event data store %q: expected an ID or ARN", i.EventDataStore)
+	}
+	if i.ResourceName != "" || i.ResourceType != "" {
+		return "", fmt.Errorf("--resource-name and --resource-type cannot be translated to a Lake query, use --where instead")
+	}
+	if i.EventSource != "" && !isValidEventSource(i.EventSource) {
+		return "", fmt.Errorf("invalid event source %q: must end with .amazonaws.com", i.EventSource)
+	}
+	if i.AccessKeyId != "" && !isValidAccessKeyID(i.AccessKeyId) {
+		return "", fmt.Errorf("invalid access key ID format")
+	}
+
+	columns, err := lakeSelectColumns(i)
+	if err != nil {
+		return "", err
+	}
+
+	conditions := []string{
+		fmt.Sprintf("eventTime >= %s", sqlString(i.StartTime.UTC().Format(lakeTimeLayout))),
+		fmt.Sprintf("eventTime <= %s", sqlString(i.EndTime.UTC().Format(lakeTimeLayout))),
+	}
+	equals := []struct{ column, value string }{
+		{"eventID", i.EventId},
+		{"eventName", i.EventName},
+		{"eventSource", i.EventSource},
+		{"userIdentity.accessKeyId", i.AccessKeyId},
+	}
+	for _, e := range equals {
+		if e.value != "" {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", e.column, sqlString(e.value)))
+		}
+	}
+	if i.UserName != "" {
+		// LookupEvents matches IAM user names as well as role session names
+		conditions = append(conditions, fmt.Sprintf(`(userIdentity.userName = %s OR userIdentity.arn LIKE %s ESCAPE '\')`,
+			sqlString(i.UserName), sqlString("%/"+sqlLikePattern(i.UserName))))
+	}
+	if i.IsReadOnlyFlagSet {
+		conditions = append(conditions, fmt.Sprintf("readOnly = %t", i.ReadOnly))
+	}
+	if i.ErrorOnly {
+		// Like hasError, error rate Insights events count as errors
+		conditions = append(conditions, fmt.Sprintf("(errorCode <> '' OR insightDetails.insightType = %s)", sqlString(apiErrorRateInsight)))
+	}
+
+	clauses, err := parseWhereClauses(i.Where)
+	if err != nil {
+		return "", err
+	}
+	for _, clause := range clauses {
+		condition, err := whereCondition(clause, i.IdentityFormat)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY eventTime DESC LIMIT %d",
+		strings.Join(columns, ", "),
+		m[1],
+		strings.Join(conditions, " AND "),
+		i.MaxResults,
+	), nil
+}
+
+// lakeEventsHandler runs the lookup against an event data store instead of the event history
+func lakeEventsHandler(i types.CloudTrailCliInput) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+	if i.PivotOn != "" {
+		return fmt.Errorf("--pivot-on is not supported with --event-data-store")
+	}
+	if i.Insights {
+		return fmt.Errorf("--insights is not supported with --event-data-store")
+	}
+	if len(i.GroupBy) > 0 {
+		return fmt.Errorf("--group-by is not supported with --event-data-store, use 'lake query' with GROUP BY instead")
+	}
+	if i.ExcludeKnownIPs {
+		return fmt.Errorf("--exclude-known-ips is not supported with --event-data-store")
+	}
+	if len(i.SourceIPs) > 0 {
+		return fmt.Errorf("--source-ip is not supported with --event-data-store, use --where SourceIPAddress=... instead")
+	}
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	statement, err := buildLakeQuery(i)
+	if err != nil {
+		return err
+	}
+	if i.PrintSQL {
+		fmt.Println(statement)
+		return nil
+	}
+
+	i.LakeQuery = statement
+	if i.PollInterval <= 0 {
+		i.PollInterval = constants.DefaultPollInterval
+	}
+	return LakeQueryHandler(i)
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/detect"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// LoginColumns is the table layout of the logins subcommand when --columns is not set
+var LoginColumns = []string{
+	"EventTime", "Username", "IdentityType", "LoginResult", "MFAUsed", "SourceIPAddress", "UserAgent", "ErrorMessage",
+}
+
+// loginSummary aggregates the console logins of one user
+type loginSummary struct {
+	User        string
+	Succeeded   int
+	Failed      int
+	WithoutMFA  int
+	SourceIPs   []string
+	LastSuccess string
+	LastFailure string
+}
+
+// summarizeLogins aggregates console logins per user, most failures first
+func summarizeLogins(logins []*types.Event, config types.CloudTrailCliInput) []*loginSummary {
+	byUser := make(map[string]*loginSummary)
+	for _, e := range logins {
+		user := formatIdentityName(e.Record.UserIdentity, config.IdentityFormat)
+		summary, ok := byUser[user]
+		if !ok {
+			summary = &loginSummary{User: user}
+			byUser[user] = summary
+		}
+
+		if detect.ConsoleLoginResult(e.Record) == "Success" {
+			summary.Succeeded++
+			if e.Record.AdditionalEventData == nil || e.Record.AdditionalEventData.MFAUsed != "Yes" {
+				summary.WithoutMFA++
+			}
+			if e.Record.EventTime > summary.LastSuccess {
+				summary.LastSuccess = e.Record.EventTime
+			}
+		} else {
+			summary.Failed++
+			if e.Record.EventTime > summary.LastFailure {
+				summary.LastFailure = e.Record.EventTime
+			}
+		}
+		if ip := e.Record.SourceIPAddress; ip != "" && !containsString(summary.SourceIPs, ip) {
+			summary.SourceIPs = append(summary.SourceIPs, ip)
+		}
+	}
+
+	summaries := make([]*loginSummary, 0, len(byUser))
+	for _, summary := range byUser {
+		sort.Strings(summary.SourceIPs)
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(a, b int) bool {
+		if summaries[a].Failed != summaries[b].Failed {
+			return summaries[a].Failed > summaries[b].Failed
+		}
+		return summaries[a].User < summaries[b].User
+	})
+	return summaries
+}
+
+// renderLoginSummary prints the per-user login summary table
+func renderLoginSummary(w io.Writer, summaries []*loginSummary, config types.CloudTrailCliInput) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Logins per user")
+	t.AppendHeader(table.Row{"User", "Succeeded", "Failed", "WithoutMFA", "SourceIPAddresses", "LastSuccess", "LastFailure"})
+
+	var rows []table.Row
+	for _, s := range summaries {
+		rows = append(rows, table.Row{
+			s.User, s.Succeeded, s.Failed, s.WithoutMFA, strings.Join(s.SourceIPs, "\n"), s.LastSuccess, s.LastFailure,
+		})
+	}
+	t.AppendRows(displayRows(config, rows))
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
+
+// loginsInput looks up console logins unless another lookup filter was given,
+// in which case console logins are picked client-side
+func loginsInput(i types.CloudTrailCliInput) types.CloudTrailCliInput {
+	if i.EventId == "" && i.EventName == "" && !i.IsReadOnlyFlagSet && i.UserName == "" &&
+		i.ResourceName == "" && i.ResourceType == "" && i.EventSource == "" && i.AccessKeyId == "" {
+		i.EventName = "ConsoleLogin"
+	}
+	return i
+}
+
+// loginsHandlerWithLookup allows injection of LookupEvents function for testing
+func loginsHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc, w io.Writer) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+	if i.EventDataStore != "" {
+		return fmt.Errorf("logins is not supported with --event-data-store")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	input, err := buildCloudTrailInput(loginsInput(i))
+	if err != nil {
+		return err
+	}
+
+	events, err := lookupFunc(ctx, svc, input, i.MaxResults)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+
+	columns, err := resolveColumns(i.Columns)
+	if err != nil {
+		return err
+	}
+	var logins []*types.Event
+	var rows []table.Row
+	for _, e := range filterEvents(events, i) {
+		if e.Record.EventName != "ConsoleLogin" {
+			continue
+		}
+		logins = append(logins, e)
+		row := make(table.Row, 0, len(columns))
+		for _, cell := range buildCells(e, columns, i) {
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	if err := renderRows(w, i.Output, columnHeader(i), rows); err != nil {
+		return err
+	}
+
+	// The summary and detections only fit the table layout
+	if i.Output != "" && i.Output != "table" {
+		return nil
+	}
+	if len(logins) == 0 {
+		fmt.Fprintln(w, "No console login found")
+		return nil
+	}
+	renderLoginSummary(w, summarizeLogins(logins, i), i)
+
+	rules, _ := detect.SelectRules([]string{"console-login-after-failures"})
+	records := make([]*types.CloudTrailEvent, 0, len(logins))
+	for _, e := range logins {
+		records = append(records, e.Record)
+	}
+	if findings := detect.Evaluate(rules, records); len(findings) > 0 {
+		renderFindings(w, findings, i)
+	}
+	return nil
+}
+
+// LoginsHandler reports console logins, per-user failures and logins after repeated failures
+func LoginsHandler(i types.CloudTrailCliInput) error {
+	return loginsHandlerWithLookup(i, LookupEvents, os.Stdout)
+}
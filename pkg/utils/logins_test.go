@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func newLoginEvent(id, eventTime, user, ip, result, mfa string) ctypes.Event {
+	return ctypes.Event{CloudTrailEvent: aws.String(fmt.Sprintf(`{
+		"eventID": %q,
+		"eventName": "ConsoleLogin",
+		"eventSource": "signin.amazonaws.com",
+		"eventTime": %q,
+		"sourceIPAddress": %q,
+		"userIdentity": {"type": "IAMUser", "userName": %q, "arn": "arn:aws:iam::123456789012:user/%s"},
+		"responseElements": {"ConsoleLogin": %q},
+		"additionalEventData": {"MFAUsed": %q}
+	}`, id, eventTime, ip, user, user, result, mfa))}
+}
+
+func loginEvents() []ctypes.Event {
+	return []ctypes.Event{
+		newLoginEvent("s2", "2025-05-12T09:00:00Z", "bob", "198.51.100.7", "Success", "Yes"),
+		newLoginEvent("s1", "2025-05-12T00:03:00Z", "alice", "203.0.113.10", "Success", "No"),
+		newLoginEvent("f3", "2025-05-12T00:02:00Z", "alice", "203.0.113.10", "Failure", "No"),
+		newLoginEvent("f2", "2025-05-12T00:01:00Z", "alice", "203.0.113.10", "Failure", "No"),
+		newLoginEvent("f1", "2025-05-12T00:00:00Z", "alice", "192.0.2.1", "Failure", "No"),
+		newLoginEvent("f0", "2025-05-12T00:00:00Z", "alice", "203.0.113.10", "Failure", "No"),
+		{CloudTrailEvent: aws.String(`{"eventID": "other", "eventName": "SwitchRole", "eventSource": "signin.amazonaws.com"}`)},
+	}
+}
+
+func TestSummarizeLogins(t *testing.T) {
+	var logins []*types.Event
+	for _, e := range filterEvents(loginEvents(), types.CloudTrailCliInput{}) {
+		if e.Record.EventName == "ConsoleLogin" {
+			logins = append(logins, e)
+		}
+	}
+
+	var summaries []loginSummary
+	for _, summary := range summarizeLogins(logins, types.CloudTrailCliInput{}) {
+		summaries = append(summaries, *summary)
+	}
+
+	expected := []loginSummary{
+		{
+			User:        "alice",
+			Succeeded:   1,
+			Failed:      4,
+			WithoutMFA:  1,
+			SourceIPs:   []string{"192.0.2.1", "203.0.113.10"},
+			LastSuccess: "2025-05-12T00:03:00Z",
+			LastFailure: "2025-05-12T00:02:00Z",
+		},
+		{
+			User:        "bob",
+			Succeeded:   1,
+			SourceIPs:   []string{"198.51.100.7"},
+			LastSuccess: "2025-05-12T09:00:00Z",
+		},
+	}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("expected %+v, got %+v", expected, summaries)
+	}
+}
+
+func TestLoginsInput(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    types.CloudTrailCliInput
+		expected types.CloudTrailCliInput
+	}{
+		{
+			name:     "Console logins by default",
+			input:    types.CloudTrailCliInput{},
+			expected: types.CloudTrailCliInput{EventName: "ConsoleLogin"},
+		},
+		{
+			name:     "Other lookup filter",
+			input:    types.CloudTrailCliInput{UserName: "alice"},
+			expected: types.CloudTrailCliInput{UserName: "alice"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			if got := loginsInput(tc.input); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoginsHandler(t *testing.T) {
+	var requested *cloudtrail.LookupEventsInput
+	lookup := func(ctx context.Context, svc *cloudtrail.Client, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error) {
+		requested = input
+		return loginEvents(), nil
+	}
+
+	var buf bytes.Buffer
+	i := types.CloudTrailCliInput{Region: "us-east-1", MaxResults: 50, Columns: LoginColumns}
+	if err := loginsHandlerWithLookup(i, lookup, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requested.LookupAttributes) != 1 || aws.ToString(requested.LookupAttributes[0].AttributeValue) != "ConsoleLogin" {
+		t.Errorf("expected a ConsoleLogin lookup, got %+v", requested.LookupAttributes)
+	}
+	output := buf.String()
+	for _, expected := range []string{"Logins per user", "console-login-after-failures", "f0 ", "s1 "} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q:\n%s", expected, output)
+		}
+	}
+	if strings.Count(output, "IAMUser") != 6 {
+		t.Errorf("expected only console logins:\n%s", output)
+	}
+}
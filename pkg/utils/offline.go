@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cloudTrailLogFile is the layout of log files delivered by CloudTrail to S3
+type cloudTrailLogFile struct {
+	Records []json.RawMessage `json:"Records"`
+}
+
+// listEventFiles expands directories into the JSON (optionally gzipped) files they contain
+func listEventFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := strings.ToLower(d.Name())
+			if !d.IsDir() && (strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read events from %s: %w", path, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readEventFile returns the raw records of a CloudTrail log file. Besides the
+// {"Records": [...]} layout, a JSON array of records and a single record are accepted.
+func readEventFile(path string) ([]json.RawMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		var records []json.RawMessage
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+
+	var logFile cloudTrailLogFile
+	if err := json.Unmarshal(data, &logFile); err != nil {
+		return nil, err
+	}
+	if logFile.Records != nil {
+		return logFile.Records, nil
+	}
+	return []json.RawMessage{data}, nil
+}
+
+// readEventFiles loads every record from the given CloudTrail log files and directories
+func readEventFiles(paths []string) ([]json.RawMessage, error) {
+	files, err := listEventFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []json.RawMessage
+	for _, file := range files {
+		fileRecords, err := readEventFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse CloudTrail log file %s: %w", file, err)
+		}
+		records = append(records, fileRecords...)
+	}
+	return records, nil
+}
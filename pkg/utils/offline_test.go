@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEventFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	logFile := `{"Records": [{"eventID": "a"}, {"eventID": "b"}]}`
+	f, err := os.Create(filepath.Join(dir, "123456789012_CloudTrail_us-east-1_20250512T0000Z_abc.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(logFile)); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	f.Close()
+
+	if err := os.WriteFile(filepath.Join(dir, "array.json"), []byte(`[{"eventID": "c"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "single.json"), []byte(`{"eventID": "d"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := readEventFiles([]string{dir})
+	if err != nil {
+		t.Fatalf("readEventFiles() failed: %v", err)
+	}
+	if len(records) != 4 {
+		t.Errorf("readEventFiles() returned %d records, want 4", len(records))
+	}
+}
+
+func TestReadEventFilesInvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(path, []byte(`{"Records": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readEventFiles([]string{path}); err == nil {
+		t.Error("expected error for malformed log file")
+	}
+}
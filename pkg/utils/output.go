@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// orderedRow marshals a row as a JSON object keeping the column order
+type orderedRow struct {
+	header table.Row
+	row    table.Row
+}
+
+func (r orderedRow) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for idx, column := range r.header {
+		if idx > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(fmt.Sprint(column))
+		if err != nil {
+			return nil, err
+		}
+		var cell interface{}
+		if idx < len(r.row) {
+			cell = r.row[idx]
+		}
+		value, err := json.Marshal(cell)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// renderRows renders rows as a table, as CSV or as a JSON array of objects
+func renderRows(w io.Writer, format string, header table.Row, rows []table.Row) error {
+	if format == "json" {
+		objects := make([]orderedRow, 0, len(rows))
+		for _, row := range rows {
+			objects = append(objects, orderedRow{header: header, row: row})
+		}
+		output, err := json.MarshalIndent(objects, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to render JSON output: %w", err)
+		}
+		fmt.Fprintln(w, string(output))
+		return nil
+	}
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		for _, row := range append([]table.Row{header}, rows...) {
+			record := make([]string, 0, len(row))
+			for _, cell := range row {
+				record = append(record, fmt.Sprint(cell))
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("unable to render CSV output: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(header)
+	for _, row := range rows {
+		t.AppendRow(row)
+	}
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+	return nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestRenderRows(t *testing.T) {
+	header := table.Row{"EventName", "Username"}
+	rows := []table.Row{
+		{"PutObject", "alice"},
+		{"AssumeRole", `bob "the admin"`},
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   "csv",
+			expected: "EventName,Username\nPutObject,alice\nAssumeRole,\"bob \"\"the admin\"\"\"\n",
+		},
+		{
+			format: "json",
+			expected: `[
+  {
+    "EventName": "PutObject",
+    "Username": "alice"
+  },
+  {
+    "EventName": "AssumeRole",
+    "Username": "bob \"the admin\""
+  }
+]
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := renderRows(&b, tc.format, header, rows); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if b.String() != tc.expected {
+				t.Errorf("Unexpected output:\n%s", b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// pivotSeed is a value shared by first query results and the time span it was seen in
+type pivotSeed struct {
+	value       string
+	count       int
+	first, last time.Time
+}
+
+// pivotValue returns the value of an event that related events are looked up by
+func pivotValue(e *types.Event, pivotOn string) string {
+	switch pivotOn {
+	case "user":
+		return e.Username
+	case "access-key":
+		return e.Record.UserIdentity.AccessKeyId
+	case "ip":
+		return e.Record.SourceIPAddress
+	case "request-id":
+		return e.Record.RequestId
+	}
+	return ""
+}
+
+// collectPivotSeeds groups events by pivot value, most frequent values first
+func collectPivotSeeds(events []*types.Event, pivotOn string) []pivotSeed {
+	seeds := make(map[string]*pivotSeed)
+	for _, e := range events {
+		value := pivotValue(e, pivotOn)
+		if value == "" {
+			continue
+		}
+		eventTime, err := time.Parse(time.RFC3339, e.Record.EventTime)
+		if err != nil {
+			continue
+		}
+
+		seed, ok := seeds[value]
+		if !ok {
+			seed = &pivotSeed{value: value, first: eventTime, last: eventTime}
+			seeds[value] = seed
+		}
+		seed.count++
+		if eventTime.Before(seed.first) {
+			seed.first = eventTime
+		}
+		if eventTime.After(seed.last) {
+			seed.last = eventTime
+		}
+	}
+
+	sorted := make([]pivotSeed, 0, len(seeds))
+	for _, seed := range seeds {
+		sorted = append(sorted, *seed)
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].count != sorted[b].count {
+			return sorted[a].count > sorted[b].count
+		}
+		return sorted[a].value < sorted[b].value
+	})
+	return sorted
+}
+
+// pivotInput returns the lookup input of a pivot: same window and display
+// settings, with the original lookup and client-side filters cleared.
+// ExcludeKnownIPs is kept: it hides the traffic of our own infrastructure
+// rather than selecting the events to pivot from.
+func pivotInput(i types.CloudTrailCliInput) types.CloudTrailCliInput {
+	i.EventId = ""
+	i.EventName = ""
+	i.UserName = ""
+	i.ResourceName = ""
+	i.ResourceType = ""
+	i.EventSource = ""
+	i.AccessKeyId = ""
+	i.IsReadOnlyFlagSet = false
+	i.ErrorOnly = false
+	i.Where = nil
+	i.SourceIPs = nil
+	return i
+}
+
+// pivotWindow widens a time span by the pivot window, without going past now
+func pivotWindow(first, last time.Time, window time.Duration) (time.Time, time.Time) {
+	end := last.Add(window)
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	return first.Add(-window), end
+}
+
+// fetchRelatedEvents looks up the events related to the seeds. Users and access
+// keys are lookup attributes, so one lookup is issued per value. Source IPs and
+// request IDs are not, so a single lookup over the whole span is filtered instead.
+func fetchRelatedEvents(ctx context.Context, fetch eventFetcher, i types.CloudTrailCliInput, seeds []pivotSeed) ([]ctypes.Event, error) {
+	if len(seeds) > constants.MaxPivotValues {
+		log.Printf("Pivoting on the %d most frequent values out of %d", constants.MaxPivotValues, len(seeds))
+		seeds = seeds[:constants.MaxPivotValues]
+	}
+
+	var related []ctypes.Event
+	switch i.PivotOn {
+	case "user", "access-key":
+		for _, seed := range seeds {
+			input := pivotInput(i)
+			if i.PivotOn == "user" {
+				input.UserName = seed.value
+			} else {
+				input.AccessKeyId = seed.value
+			}
+			input.StartTime, input.EndTime = pivotWindow(seed.first, seed.last, i.PivotWindow)
+
+			request, err := buildCloudTrailInput(input)
+			if err != nil {
+				return nil, err
+			}
+			events, err := fetch(ctx, request, i.MaxResults)
+			if err != nil {
+				return nil, err
+			}
+			related = append(related, events...)
+		}
+	default:
+		values := make(map[string]bool)
+		first, last := seeds[0].first, seeds[0].last
+		for _, seed := range seeds {
+			values[seed.value] = true
+			if seed.first.Before(first) {
+				first = seed.first
+			}
+			if seed.last.After(last) {
+				last = seed.last
+			}
+		}
+
+		input := pivotInput(i)
+		input.StartTime, input.EndTime = pivotWindow(first, last, i.PivotWindow)
+		request, err := buildCloudTrailInput(input)
+		if err != nil {
+			return nil, err
+		}
+		events, err := fetch(ctx, request, i.MaxResults)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			record, err := parseCloudTrailEvent(event)
+			if err != nil {
+				continue
+			}
+			if values[pivotValue(newEvent(event, record), i.PivotOn)] {
+				related = append(related, event)
+			}
+		}
+	}
+	return related, nil
+}
+
+// mergeEvents de-duplicates events by EventId and orders them newest first
+func mergeEvents(groups ...[]ctypes.Event) []ctypes.Event {
+	seen := make(map[string]bool)
+	var merged []ctypes.Event
+	for _, events := range groups {
+		for _, event := range events {
+			id := aws.ToString(event.EventId)
+			if id != "" && seen[id] {
+				continue
+			}
+			seen[id] = true
+			merged = append(merged, event)
+		}
+	}
+
+	sort.SliceStable(merged, func(a, b int) bool {
+		return aws.ToTime(merged[a].EventTime).After(aws.ToTime(merged[b].EventTime))
+	})
+	return merged
+}
+
+// pivotEvents extends the results of the first query with their related events
+func pivotEvents(ctx context.Context, fetch eventFetcher, i types.CloudTrailCliInput, events []ctypes.Event) ([]ctypes.Event, error) {
+	// Only the events passing the client-side filters seed the pivot
+	filtered := filterEvents(events, i)
+	seedIds := make(map[string]bool, len(filtered))
+	for _, e := range filtered {
+		seedIds[e.Record.EventId] = true
+	}
+	var seedEvents []ctypes.Event
+	for _, event := range events {
+		if seedIds[aws.ToString(event.EventId)] {
+			seedEvents = append(seedEvents, event)
+		}
+	}
+
+	seeds := collectPivotSeeds(filtered, i.PivotOn)
+	if len(seeds) == 0 {
+		return seedEvents, nil
+	}
+
+	related, err := fetchRelatedEvents(ctx, fetch, i, seeds)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve related CloudTrail events. Please check your permissions and try again")
+	}
+	return mergeEvents(seedEvents, related), nil
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/iprange"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func newPivotEvent(id, eventTime, username, accessKeyId, sourceIP, errorCode string) ctypes.Event {
+	at, _ := time.Parse(time.RFC3339, eventTime)
+	return ctypes.Event{
+		EventId:   aws.String(id),
+		EventTime: aws.Time(at),
+		Username:  aws.String(username),
+		CloudTrailEvent: aws.String(fmt.Sprintf(`{
+			"eventID": %q,
+			"eventTime": %q,
+			"sourceIPAddress": %q,
+			"errorCode": %q,
+			"userIdentity": {"accessKeyId": %q}
+		}`, id, eventTime, sourceIP, errorCode, accessKeyId)),
+	}
+}
+
+func eventIds(events []ctypes.Event) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, aws.ToString(event.EventId))
+	}
+	return ids
+}
+
+func TestPivotEvents(t *testing.T) {
+	seeds := []ctypes.Event{
+		newPivotEvent("seed-1", "2025-05-12T10:00:00Z", "alice", "AKIAALICE", "203.0.113.10", "AccessDenied"),
+		newPivotEvent("seed-2", "2025-05-12T09:00:00Z", "bob", "AKIABOB", "198.51.100.7", ""),
+	}
+	byUser := map[string][]ctypes.Event{
+		"alice": {
+			newPivotEvent("alice-1", "2025-05-12T10:30:00Z", "alice", "AKIAALICE", "203.0.113.10", ""),
+			seeds[0],
+		},
+	}
+	unfiltered := []ctypes.Event{
+		newPivotEvent("ip-1", "2025-05-12T10:20:00Z", "carol", "AKIACAROL", "203.0.113.10", ""),
+		newPivotEvent("other", "2025-05-12T10:10:00Z", "dave", "AKIADAVE", "192.0.2.1", ""),
+		seeds[0],
+	}
+
+	testCases := []struct {
+		name     string
+		pivotOn  string
+		expected []string
+		windows  []string
+	}{
+		{
+			name:     "Fan out per user",
+			pivotOn:  "user",
+			expected: []string{"alice-1", "seed-1"},
+			windows:  []string{"alice 2025-05-12T09:00:00Z 2025-05-12T11:00:00Z"},
+		},
+		{
+			name:     "Filter one lookup by source IP",
+			pivotOn:  "ip",
+			expected: []string{"ip-1", "seed-1"},
+			windows:  []string{" 2025-05-12T09:00:00Z 2025-05-12T11:00:00Z"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var windows []string
+			fetch := func(ctx context.Context, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error) {
+				var value string
+				if len(input.LookupAttributes) > 0 {
+					value = aws.ToString(input.LookupAttributes[0].AttributeValue)
+				}
+				windows = append(windows, fmt.Sprintf("%s %s %s", value,
+					input.StartTime.Format(time.RFC3339), input.EndTime.Format(time.RFC3339)))
+				if value == "" {
+					return unfiltered, nil
+				}
+				return byUser[value], nil
+			}
+
+			// Only the AccessDenied seed passes the filters of the first query
+			input := types.CloudTrailCliInput{
+				PivotOn:     tc.pivotOn,
+				PivotWindow: time.Hour,
+				ErrorOnly:   true,
+				MaxResults:  100,
+			}
+			events, err := pivotEvents(context.Background(), fetch, input, seeds)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := eventIds(events); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected events %v, got %v", tc.expected, got)
+			}
+			if !reflect.DeepEqual(windows, tc.windows) {
+				t.Errorf("Expected lookups %v, got %v", tc.windows, windows)
+			}
+		})
+	}
+}
+
+func TestPivotInputFilters(t *testing.T) {
+	testdata := filepath.Join("..", "iprange", "testdata")
+	tagger, err := iprange.New("", []string{filepath.Join(testdata, "labels.txt")})
+	if err != nil {
+		t.Fatalf("failed to load IP labels: %v", err)
+	}
+
+	seeds := []ctypes.Event{
+		newPivotEvent("seed", "2025-05-12T10:00:00Z", "alice", "AKIAALICE", "203.0.113.10", ""),
+		newPivotEvent("elsewhere", "2025-05-12T09:00:00Z", "bob", "AKIABOB", "192.0.2.1", ""),
+	}
+	related := []ctypes.Event{
+		newPivotEvent("other-ip", "2025-05-12T10:30:00Z", "alice", "AKIAALICE", "192.0.2.44", ""),
+		newPivotEvent("office", "2025-05-12T10:20:00Z", "alice", "AKIAALICE", "198.51.100.7", ""),
+		seeds[0],
+	}
+	fetch := func(ctx context.Context, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error) {
+		return related, nil
+	}
+
+	input := types.CloudTrailCliInput{
+		PivotOn:         "user",
+		PivotWindow:     time.Hour,
+		MaxResults:      100,
+		SourceIPs:       []string{"203.0.113.0/24"},
+		IPTags:          tagger,
+		ExcludeKnownIPs: true,
+	}
+	events, err := pivotEvents(context.Background(), fetch, input, seeds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The source IP filter only selects the seeds, while known IPs stay hidden
+	var got []string
+	for _, e := range filterEvents(events, pivotInput(input)) {
+		got = append(got, e.Record.EventId)
+	}
+	if expected := []string{"other-ip", "seed"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected events %v, got %v", expected, got)
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	older := newPivotEvent("older", "2025-05-12T08:00:00Z", "", "", "", "")
+	newer := newPivotEvent("newer", "2025-05-12T12:00:00Z", "", "", "", "")
+
+	merged := mergeEvents([]ctypes.Event{older, newer}, []ctypes.Event{newer, older})
+	if got := eventIds(merged); !reflect.DeepEqual(got, []string{"newer", "older"}) {
+		t.Errorf("Expected de-duplicated events newest first, got %v", got)
+	}
+}
+
+func TestCollectPivotSeeds(t *testing.T) {
+	events := filterEvents([]ctypes.Event{
+		newPivotEvent("1", "2025-05-12T08:00:00Z", "", "AKIAB", "", ""),
+		newPivotEvent("2", "2025-05-12T09:00:00Z", "", "AKIAA", "", ""),
+		newPivotEvent("3", "2025-05-12T07:00:00Z", "", "AKIAB", "", ""),
+		newPivotEvent("4", "2025-05-12T07:00:00Z", "", "", "", ""),
+	}, types.CloudTrailCliInput{})
+
+	seeds := collectPivotSeeds(events, "access-key")
+	if len(seeds) != 2 {
+		t.Fatalf("Expected 2 seeds, got %d", len(seeds))
+	}
+	if seeds[0].value != "AKIAB" || seeds[0].count != 2 {
+		t.Errorf("Expected the most frequent key first, got %+v", seeds[0])
+	}
+	if seeds[0].first.Hour() != 7 || seeds[0].last.Hour() != 8 {
+		t.Errorf("Expected span 07:00-08:00, got %v-%v", seeds[0].first, seeds[0].last)
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/policy"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// policyGenHandlerWithLookup allows injection of LookupEvents function for testing
+func policyGenHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if i.UserName == "" && i.AccessKeyId == "" {
+		return fmt.Errorf("--user-name or --access-key-id is required to generate a policy")
+	}
+	if err := validateInput(i); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	input, err := buildCloudTrailInput(i)
+	if err != nil {
+		return err
+	}
+
+	events, err := lookupFunc(ctx, svc, input, i.MaxResults)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+	if len(events) >= i.MaxResults {
+		log.Printf("Only the first %d events of the time range were read: the policy may miss actions, raise --max-results to read them all", i.MaxResults)
+	}
+
+	generator := policy.NewGenerator(i.IncludeResources)
+	for _, cloudTrailEvent := range parseCloudTrailEvents(events) {
+		generator.Add(cloudTrailEvent)
+	}
+	for _, pair := range generator.Unmapped() {
+		log.Printf("Skipping %s: not an AWS service API call", pair)
+	}
+
+	output, err := json.MarshalIndent(generator.Document(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to render policy document: %w", err)
+	}
+	fmt.Fprintln(os.Stdout, i.Redactor.Text(string(output)))
+	return nil
+}
+
+// PolicyGenHandler emits a least-privilege IAM policy from the activity of one identity
+func PolicyGenHandler(i types.CloudTrailCliInput) error {
+	return policyGenHandlerWithLookup(i, LookupEvents)
+}
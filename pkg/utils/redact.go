@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/guessi/cloudtrail-cli/pkg/redact"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// redactRows redacts the text cells of table rows
+func redactRows(r *redact.Redactor, rows []table.Row) []table.Row {
+	if r == nil {
+		return rows
+	}
+	for _, row := range rows {
+		for n, cell := range row {
+			if s, ok := cell.(string); ok {
+				row[n] = r.Text(s)
+			}
+		}
+	}
+	return rows
+}
+
+// redactPairs redacts the values of key/value pairs
+func redactPairs(r *redact.Redactor, pairs [][2]string) [][2]string {
+	if r == nil {
+		return pairs
+	}
+	for n := range pairs {
+		pairs[n][1] = r.Text(pairs[n][1])
+	}
+	return pairs
+}
+
+// redactEvent returns a copy of an event for display, leaving the original
+// untouched for pivots and filters
+func redactEvent(r *redact.Redactor, e *types.Event) (*types.Event, error) {
+	if r == nil {
+		return e, nil
+	}
+
+	data, err := json.Marshal(e.Record)
+	if err != nil {
+		return nil, err
+	}
+	data, err = r.JSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to redact event %s: %w", e.Record.EventId, err)
+	}
+	var record types.CloudTrailEvent
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	redacted := &types.Event{Record: &record, Username: r.Text(e.Username)}
+	for _, resource := range e.Resources {
+		redacted.Resources = append(redacted.Resources, types.LookupResource{
+			ResourceType: resource.ResourceType,
+			ResourceName: r.Text(resource.ResourceName),
+		})
+	}
+	return redacted, nil
+}
+
+// tableOutput reports whether values are rendered for people rather than
+// parsed by other tools
+func tableOutput(config types.CloudTrailCliInput) bool {
+	return config.Output == "" || config.Output == "table"
+}
+
+// annotateAccounts appends the aliases of the account IDs found in a value.
+// Aliases identify accounts, so they are left out when redacting. CSV and JSON
+// keep the raw value, aliases being available there as columns.
+func annotateAccounts(config types.CloudTrailCliInput, s string) string {
+	if config.Redactor != nil || !tableOutput(config) {
+		return s
+	}
+	return config.Accounts.Annotate(s)
+}
+
+// displayRows prepares the text cells of table rows for display: account IDs
+// are annotated with their aliases, or redacted
+func displayRows(config types.CloudTrailCliInput, rows []table.Row) []table.Row {
+	if config.Redactor != nil {
+		return redactRows(config.Redactor, rows)
+	}
+	if config.Accounts == nil || !tableOutput(config) {
+		return rows
+	}
+	for _, row := range rows {
+		for n, cell := range row {
+			if s, ok := cell.(string); ok {
+				row[n] = config.Accounts.Annotate(s)
+			}
+		}
+	}
+	return rows
+}
+
+// displayPairs is the key/value counterpart of displayRows
+func displayPairs(config types.CloudTrailCliInput, pairs [][2]string) [][2]string {
+	if config.Redactor != nil {
+		return redactPairs(config.Redactor, pairs)
+	}
+	if !tableOutput(config) {
+		return pairs
+	}
+	for n := range pairs {
+		pairs[n][1] = config.Accounts.Annotate(pairs[n][1])
+	}
+	return pairs
+}
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// roleEdge aggregates every assumption of one role by one principal
+type roleEdge struct {
+	Source    string
+	Target    string
+	Count     int
+	FirstSeen string
+	LastSeen  string
+	SourceIPs []string
+}
+
+// roleGraph is a directed graph of "principal assumed role" relations
+type roleGraph struct {
+	edges map[string]*roleEdge
+}
+
+func newRoleGraph() *roleGraph {
+	return &roleGraph{edges: make(map[string]*roleEdge)}
+}
+
+// getAssumingPrincipal returns the node a role assumption originates from. Sessions
+// of an assumed role collapse onto the issuing role so that chained assumptions link up.
+func getAssumingPrincipal(u types.UserIdentity) string {
+	switch u.Type {
+	case "AssumedRole":
+		if u.SessionContext.SessionIssuer.Arn != "" {
+			return u.SessionContext.SessionIssuer.Arn
+		}
+	case "AWSService":
+		if u.InvokedBy != "" {
+			return u.InvokedBy
+		}
+	}
+	return getIdentityLabel(u)
+}
+
+// add records a role assumption event in the graph
+func (g *roleGraph) add(e *types.CloudTrailEvent) {
+	target := types.LookupString(e.RequestParameters, "roleArn")
+	if target == "" {
+		return
+	}
+	source := getAssumingPrincipal(e.UserIdentity)
+
+	key := source + "\x00" + target
+	edge, ok := g.edges[key]
+	if !ok {
+		edge = &roleEdge{Source: source, Target: target, FirstSeen: e.EventTime, LastSeen: e.EventTime}
+		g.edges[key] = edge
+	}
+	edge.Count++
+	if e.EventTime < edge.FirstSeen {
+		edge.FirstSeen = e.EventTime
+	}
+	if e.EventTime > edge.LastSeen {
+		edge.LastSeen = e.EventTime
+	}
+	if e.SourceIPAddress != "" && !containsString(edge.SourceIPs, e.SourceIPAddress) {
+		edge.SourceIPs = append(edge.SourceIPs, e.SourceIPAddress)
+		sort.Strings(edge.SourceIPs)
+	}
+}
+
+// prepareDisplay annotates the principals of the graph with account aliases,
+// or replaces them and the source IPs with pseudonyms when redacting
+func (g *roleGraph) prepareDisplay(config types.CloudTrailCliInput) {
+	r := config.Redactor
+	for _, edge := range g.edges {
+		edge.Source = r.Text(annotateAccounts(config, edge.Source))
+		edge.Target = r.Text(annotateAccounts(config, edge.Target))
+		for n, ip := range edge.SourceIPs {
+			edge.SourceIPs[n] = r.Text(ip)
+		}
+	}
+}
+
+// sortedEdges returns all edges ordered by source then target
+func (g *roleGraph) sortedEdges() []*roleEdge {
+	edges := make([]*roleEdge, 0, len(g.edges))
+	for _, edge := range g.edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(a, b int) bool {
+		if edges[a].Source != edges[b].Source {
+			return edges[a].Source < edges[b].Source
+		}
+		return edges[a].Target < edges[b].Target
+	})
+	return edges
+}
+
+// nodes returns every principal and role in the graph, sorted
+func (g *roleGraph) nodes() []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, edge := range g.edges {
+		for _, node := range []string{edge.Source, edge.Target} {
+			if !seen[node] {
+				seen[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// sources returns every principal that assumed a role, sorted
+func (g *roleGraph) sources() []string {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, edge := range g.sortedEdges() {
+		if !seen[edge.Source] {
+			seen[edge.Source] = true
+			sources = append(sources, edge.Source)
+		}
+	}
+	return sources
+}
+
+// roots returns principals that were never assumed themselves
+func (g *roleGraph) roots() []string {
+	targets := make(map[string]bool)
+	for _, edge := range g.edges {
+		targets[edge.Target] = true
+	}
+
+	var roots []string
+	for _, source := range g.sources() {
+		if !targets[source] {
+			roots = append(roots, source)
+		}
+	}
+	return roots
+}
+
+// writeTree renders the graph as an indented tree rooted at the original principals
+func (g *roleGraph) writeTree(w io.Writer) {
+	children := make(map[string][]*roleEdge)
+	for _, edge := range g.sortedEdges() {
+		children[edge.Source] = append(children[edge.Source], edge)
+	}
+
+	visited := make(map[string]bool)
+	var walk func(node, prefix string, path map[string]bool)
+	walk = func(node, prefix string, path map[string]bool) {
+		visited[node] = true
+		edges := children[node]
+		for idx, edge := range edges {
+			branch, indent := "├── ", "│   "
+			if idx == len(edges)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintf(w, "%s%s%s (%dx, %s .. %s, from %s)", prefix, branch, edge.Target,
+				edge.Count, edge.FirstSeen, edge.LastSeen, strings.Join(edge.SourceIPs, ", "))
+			if path[edge.Target] {
+				fmt.Fprintln(w, " [cycle]")
+				continue
+			}
+			fmt.Fprintln(w)
+			path[edge.Target] = true
+			walk(edge.Target, prefix+indent, path)
+			delete(path, edge.Target)
+		}
+	}
+
+	for _, root := range g.roots() {
+		fmt.Fprintln(w, root)
+		walk(root, "", map[string]bool{root: true})
+	}
+	// Cycles no root leads to, such as two roles assuming each other, start
+	// from their first source not printed yet
+	for _, source := range g.sources() {
+		if !visited[source] {
+			fmt.Fprintln(w, source)
+			walk(source, "", map[string]bool{source: true})
+		}
+	}
+}
+
+// writeDOT renders the graph in Graphviz DOT format
+func (g *roleGraph) writeDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph roles {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, edge := range g.sortedEdges() {
+		fmt.Fprintf(w, "  %q -> %q [label=%q];\n", edge.Source, edge.Target, fmt.Sprintf("%dx", edge.Count))
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart
+func (g *roleGraph) writeMermaid(w io.Writer) {
+	ids := make(map[string]string)
+	fmt.Fprintln(w, "graph LR")
+	for idx, node := range g.nodes() {
+		ids[node] = fmt.Sprintf("n%d", idx)
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", ids[node], strings.ReplaceAll(node, `"`, "#quot;"))
+	}
+	for _, edge := range g.sortedEdges() {
+		fmt.Fprintf(w, "  %s -->|%dx| %s\n", ids[edge.Source], edge.Count, ids[edge.Target])
+	}
+}
+
+// write renders the graph in the requested format
+func (g *roleGraph) write(w io.Writer, format string) error {
+	switch format {
+	case "", "tree":
+		g.writeTree(w)
+	case "dot":
+		g.writeDOT(w)
+	case "mermaid":
+		g.writeMermaid(w)
+	default:
+		return validateGraphFormat(format)
+	}
+	return nil
+}
+
+// validateGraphFormat checks the requested role graph output format
+func validateGraphFormat(format string) error {
+	switch format {
+	case "", "tree", "dot", "mermaid":
+		return nil
+	}
+	return fmt.Errorf("invalid graph format %q: must be one of tree, dot, mermaid", format)
+}
+
+// collectRoleAssumptions builds the role graph from every AssumeRole* event in the window
+func collectRoleAssumptions(ctx context.Context, fetch eventFetcher, i types.CloudTrailCliInput) (*roleGraph, error) {
+	graph := newRoleGraph()
+	for _, eventName := range constants.RoleAssumptionEvents {
+		events, err := fetch(ctx, buildEventNameInput(eventName, i.StartTime, i.EndTime), i.MaxResults)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			cloudTrailEvent, err := parseCloudTrailEvent(event)
+			if err != nil {
+				continue
+			}
+			graph.add(cloudTrailEvent)
+		}
+	}
+	return graph, nil
+}
+
+// traceRoleHandlerWithLookup allows injection of LookupEvents function for testing
+func traceRoleHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+	if err := validateGraphFormat(i.GraphFormat); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	graph, err := collectRoleAssumptions(ctx, newEventFetcher(svc, lookupFunc), i)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+
+	graph.prepareDisplay(i)
+	return graph.write(os.Stdout, i.GraphFormat)
+}
+
+// TraceRoleHandler reconstructs who assumed which role, including chained assumptions
+func TraceRoleHandler(i types.CloudTrailCliInput) error {
+	return traceRoleHandlerWithLookup(i, LookupEvents)
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func newTestRoleGraph() *roleGraph {
+	graph := newRoleGraph()
+	graph.add(&types.CloudTrailEvent{
+		EventName:       "AssumeRole",
+		EventTime:       "2025-05-12T00:10:00Z",
+		SourceIPAddress: "198.51.100.7",
+		UserIdentity: types.UserIdentity{
+			Type: "IAMUser",
+			Arn:  "arn:aws:iam::123456789012:user/alice",
+		},
+		RequestParameters: map[string]interface{}{"roleArn": "arn:aws:iam::123456789012:role/Jump"},
+	})
+	graph.add(&types.CloudTrailEvent{
+		EventName:       "AssumeRole",
+		EventTime:       "2025-05-12T00:20:00Z",
+		SourceIPAddress: "198.51.100.7",
+		UserIdentity: types.UserIdentity{
+			Type: "AssumedRole",
+			Arn:  "arn:aws:sts::123456789012:assumed-role/Jump/alice",
+			SessionContext: types.SessionContext{
+				SessionIssuer: types.SessionIssuer{Arn: "arn:aws:iam::123456789012:role/Jump"},
+			},
+		},
+		RequestParameters: map[string]interface{}{"roleArn": "arn:aws:iam::210987654321:role/Admin"},
+	})
+	graph.add(&types.CloudTrailEvent{
+		EventName: "AssumeRole",
+		EventTime: "2025-05-12T00:30:00Z",
+		UserIdentity: types.UserIdentity{
+			Type:      "AWSService",
+			InvokedBy: "lambda.amazonaws.com",
+		},
+		RequestParameters: map[string]interface{}{"roleArn": "arn:aws:iam::123456789012:role/Fn"},
+	})
+	return graph
+}
+
+func TestRoleGraphChainedAssumption(t *testing.T) {
+	graph := newTestRoleGraph()
+
+	edges := graph.sortedEdges()
+	if len(edges) != 3 {
+		t.Fatalf("sortedEdges() returned %d edges, want 3", len(edges))
+	}
+
+	roots := graph.roots()
+	if len(roots) != 2 || roots[0] != "arn:aws:iam::123456789012:user/alice" || roots[1] != "lambda.amazonaws.com" {
+		t.Errorf("roots() = %v, want [alice lambda.amazonaws.com]", roots)
+	}
+}
+
+func TestRoleGraphTreeCycles(t *testing.T) {
+	assumeRole := func(source, target string) *types.CloudTrailEvent {
+		return &types.CloudTrailEvent{
+			EventName: "AssumeRole",
+			EventTime: "2025-05-12T00:10:00Z",
+			UserIdentity: types.UserIdentity{
+				Type: "AssumedRole",
+				Arn:  "arn:aws:sts::123456789012:assumed-role/" + source + "/session",
+				SessionContext: types.SessionContext{
+					SessionIssuer: types.SessionIssuer{Arn: "arn:aws:iam::123456789012:role/" + source},
+				},
+			},
+			RequestParameters: map[string]interface{}{"roleArn": "arn:aws:iam::123456789012:role/" + target},
+		}
+	}
+
+	// A -> B has a root, C <-> D is a cycle no root leads to
+	graph := newRoleGraph()
+	graph.add(assumeRole("A", "B"))
+	graph.add(assumeRole("C", "D"))
+	graph.add(assumeRole("D", "C"))
+
+	var buf bytes.Buffer
+	if err := graph.write(&buf, "tree"); err != nil {
+		t.Fatalf("write() failed: %v", err)
+	}
+
+	expected := []string{
+		"arn:aws:iam::123456789012:role/A",
+		"└── arn:aws:iam::123456789012:role/B (1x",
+		"arn:aws:iam::123456789012:role/C",
+		"└── arn:aws:iam::123456789012:role/D (1x",
+		"    └── arn:aws:iam::123456789012:role/C (1x",
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("write() printed %d lines, want %d:\n%s", len(lines), len(expected), buf.String())
+	}
+	for idx, want := range expected {
+		if !strings.HasPrefix(lines[idx], want) {
+			t.Errorf("line %d = %q, want prefix %q", idx, lines[idx], want)
+		}
+	}
+	if !strings.HasSuffix(lines[4], "[cycle]") {
+		t.Errorf("expected the cycle to be marked: %q", lines[4])
+	}
+}
+
+func TestRoleGraphWrite(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		contains []string
+	}{
+		{
+			name:   "Tree nests chained roles",
+			format: "tree",
+			contains: []string{
+				"arn:aws:iam::123456789012:user/alice\n└── arn:aws:iam::123456789012:role/Jump (1x",
+				"    └── arn:aws:iam::210987654321:role/Admin (1x",
+			},
+		},
+		{
+			name:   "Graphviz DOT",
+			format: "dot",
+			contains: []string{
+				"digraph roles {",
+				`"arn:aws:iam::123456789012:role/Jump" -> "arn:aws:iam::210987654321:role/Admin" [label="1x"];`,
+			},
+		},
+		{
+			name:   "Mermaid flowchart",
+			format: "mermaid",
+			contains: []string{
+				"graph LR",
+				`["lambda.amazonaws.com"]`,
+				"-->|1x|",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := newTestRoleGraph().write(&buf, tc.format); err != nil {
+				t.Fatalf("write() failed: %v", err)
+			}
+			for _, want := range tc.contains {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("write(%q) output missing %q:\n%s", tc.format, want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGraphFormat(t *testing.T) {
+	if err := validateGraphFormat("svg"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if err := validateGraphFormat("mermaid"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// credentialLink ties a temporary access key to the event that issued it
+type credentialLink struct {
+	AccessKeyId string
+	Issuer      *types.CloudTrailEvent
+}
+
+// issuedAccessKeyId returns the access key id handed out by a credential issuing event
+func issuedAccessKeyId(e *types.CloudTrailEvent) string {
+	return types.LookupString(e.ResponseElements, "credentials", "accessKeyId")
+}
+
+// buildAttributeInput creates a LookupEvents request filtered by a single lookup attribute
+func buildAttributeInput(key ctypes.LookupAttributeKey, value string, startTime, endTime time.Time) *cloudtrail.LookupEventsInput {
+	return &cloudtrail.LookupEventsInput{
+		StartTime: &startTime,
+		EndTime:   &endTime,
+		LookupAttributes: []ctypes.LookupAttribute{
+			{
+				AttributeKey:   key,
+				AttributeValue: aws.String(value),
+			},
+		},
+		MaxResults: getBatchSize(constants.DefaultBatchSize),
+	}
+}
+
+// buildEventNameInput creates a LookupEvents request filtered by a single event name
+func buildEventNameInput(eventName string, startTime, endTime time.Time) *cloudtrail.LookupEventsInput {
+	return buildAttributeInput(ctypes.LookupAttributeKeyEventName, eventName, startTime, endTime)
+}
+
+// issuerSearch looks up the events that issued temporary access keys, scanning
+// at most a budget of events over the whole credential chain
+type issuerSearch struct {
+	fetch     eventFetcher
+	remaining int
+	truncated bool
+}
+
+func newIssuerSearch(fetch eventFetcher, budget int) *issuerSearch {
+	return &issuerSearch{fetch: fetch, remaining: budget}
+}
+
+// find returns the STS event whose response handed out an access key. The
+// event history lists the issued key as a resource of that event, so it is
+// looked up by resource name first. Events recorded without it are found by
+// scanning the STS events preceding the first use of the key.
+func (s *issuerSearch) find(ctx context.Context, accessKeyId string, usedAt time.Time) (*types.CloudTrailEvent, error) {
+	startTime := usedAt.Add(-constants.MaxCredentialLifetime)
+
+	inputs := []*cloudtrail.LookupEventsInput{
+		buildAttributeInput(ctypes.LookupAttributeKeyResourceName, accessKeyId, startTime, usedAt),
+	}
+	for _, eventName := range constants.CredentialIssuingEvents {
+		inputs = append(inputs, buildEventNameInput(eventName, startTime, usedAt))
+	}
+
+	truncated := false
+	for _, input := range inputs {
+		if s.remaining <= 0 {
+			truncated = true
+			break
+		}
+		limit := s.remaining
+		events, err := s.fetch(ctx, input, limit)
+		if err != nil {
+			return nil, err
+		}
+		s.remaining -= len(events)
+		if len(events) >= limit {
+			truncated = true
+		}
+
+		for _, event := range events {
+			cloudTrailEvent, err := parseCloudTrailEvent(event)
+			if err != nil {
+				continue
+			}
+			if issuedAccessKeyId(cloudTrailEvent) == accessKeyId {
+				return cloudTrailEvent, nil
+			}
+		}
+	}
+
+	s.truncated = s.truncated || truncated
+	return nil, nil
+}
+
+// traceCredentialChain follows a temporary access key back through every issuing event
+// until it reaches a long-term key, a console session or an issuer outside the window.
+// The returned chain starts with the issuer of the given key.
+func traceCredentialChain(ctx context.Context, search *issuerSearch, accessKeyId string, usedAt time.Time) ([]credentialLink, error) {
+	var chain []credentialLink
+	visited := make(map[string]bool)
+
+	for len(chain) < constants.MaxCredentialChainHops && !visited[accessKeyId] {
+		visited[accessKeyId] = true
+
+		issuer, err := search.find(ctx, accessKeyId, usedAt)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, credentialLink{AccessKeyId: accessKeyId, Issuer: issuer})
+
+		// Only temporary credentials were themselves issued by an STS call
+		parentKey := issuer.UserIdentity.AccessKeyId
+		if !strings.HasPrefix(parentKey, "ASIA") {
+			break
+		}
+		issuedAt, err := time.Parse(time.RFC3339, issuer.EventTime)
+		if err != nil {
+			break
+		}
+		accessKeyId, usedAt = parentKey, issuedAt
+	}
+
+	return chain, nil
+}
+
+// earliestEventTime returns the time of the oldest event, or the fallback when none parse
+func earliestEventTime(events []ctypes.Event, fallback time.Time) time.Time {
+	earliest := fallback
+	for _, event := range events {
+		if event.EventTime != nil && event.EventTime.Before(earliest) {
+			earliest = *event.EventTime
+		}
+	}
+	return earliest
+}
+
+// getIdentityLabel returns the most specific label available for the caller of an event
+func getIdentityLabel(u types.UserIdentity) string {
+	if u.Arn != "" {
+		return u.Arn
+	}
+	if name := getDisplayUserName(u); name != "-" {
+		return name
+	}
+	if u.PrincipalId != "" {
+		return u.PrincipalId
+	}
+	return u.Type
+}
+
+// getGrantedIdentity returns the role or federated user the issued credentials belong to
+func getGrantedIdentity(e *types.CloudTrailEvent) string {
+	if arn := types.LookupString(e.ResponseElements, "assumedRoleUser", "arn"); arn != "" {
+		return arn
+	}
+	if arn := types.LookupString(e.ResponseElements, "federatedUser", "arn"); arn != "" {
+		return arn
+	}
+	if roleArn := types.LookupString(e.RequestParameters, "roleArn"); roleArn != "" {
+		return roleArn
+	}
+	return getIdentityLabel(e.UserIdentity)
+}
+
+// getSourceIdentity returns the sourceIdentity carried by a role assumption, if any
+func getSourceIdentity(e *types.CloudTrailEvent) string {
+	if s := types.LookupString(e.ResponseElements, "sourceIdentity"); s != "" {
+		return s
+	}
+	return types.LookupString(e.RequestParameters, "sourceIdentity")
+}
+
+// renderCredentialChain prints the issuing chain starting from its origin
+func renderCredentialChain(chain []credentialLink, config types.CloudTrailCliInput) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{
+		"Hop", "EventTime", "EventName", "IssuedBy", "CallerAccessKeyId", "SourceIPAddress",
+		"MfaAuthenticated", "GrantedIdentity", "SourceIdentity", "IssuedAccessKeyId",
+	})
+
+	var rows []table.Row
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		issuer := chain[idx].Issuer
+		rows = append(rows, table.Row{
+			len(chain) - idx,
+			issuer.EventTime,
+			issuer.EventName,
+			getIdentityLabel(issuer.UserIdentity),
+			issuer.UserIdentity.AccessKeyId,
+			issuer.SourceIPAddress,
+			issuer.UserIdentity.SessionContext.Attributes.MfaAuthenticated,
+			getGrantedIdentity(issuer),
+			getSourceIdentity(issuer),
+			chain[idx].AccessKeyId,
+		})
+	}
+	t.AppendRows(displayRows(config, rows))
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
+
+// sessionHandlerWithLookup allows injection of LookupEvents function for testing
+func sessionHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if i.AccessKeyId == "" {
+		return fmt.Errorf("--access-key-id is required to reconstruct a session")
+	}
+	if err := validateInput(i); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	input, err := buildCloudTrailInput(i)
+	if err != nil {
+		return err
+	}
+
+	fetch := newEventFetcher(svc, lookupFunc)
+
+	events, err := fetch(ctx, input, i.MaxResults)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+	if err := renderRows(os.Stdout, i.Output, columnHeader(i), processEvents(events, i)); err != nil {
+		return err
+	}
+
+	search := newIssuerSearch(fetch, constants.MaxIssuerSearchResults)
+	chain, err := traceCredentialChain(ctx, search, i.AccessKeyId, earliestEventTime(events, i.EndTime))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve credential issuing events. Please check your permissions and try again")
+	}
+	if len(chain) == 0 {
+		if search.truncated {
+			fmt.Printf("\nSearch truncated: no issuing event found for %s among the %d STS events scanned\n", i.Redactor.Text(i.AccessKeyId), constants.MaxIssuerSearchResults)
+			return nil
+		}
+		fmt.Printf("\nNo issuing event found for %s (long-term key, console session, or issued outside the lookup window/region)\n", i.Redactor.Text(i.AccessKeyId))
+		return nil
+	}
+
+	origin := chain[len(chain)-1].Issuer
+	fmt.Printf("\nCredential chain for %s (origin: %s from %s)\n",
+		i.Redactor.Text(i.AccessKeyId), i.Redactor.Text(annotateAccounts(i, getIdentityLabel(origin.UserIdentity))), i.Redactor.Text(origin.SourceIPAddress))
+	renderCredentialChain(chain, i)
+	if search.truncated {
+		fmt.Printf("Search truncated: no issuing event found for %s among the %d STS events scanned, the chain may start earlier\n",
+			i.Redactor.Text(origin.UserIdentity.AccessKeyId), constants.MaxIssuerSearchResults)
+	}
+	return nil
+}
+
+// SessionHandler reconstructs everything done with an access key and how it was issued
+func SessionHandler(i types.CloudTrailCliInput) error {
+	return sessionHandlerWithLookup(i, LookupEvents)
+}
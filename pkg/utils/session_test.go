@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// fakeFetcher serves canned events keyed by the value of the single lookup
+// attribute, at most maxResults of them, and records the values looked up
+func fakeFetcher(byValue map[string][]ctypes.Event, lookups *[]string) eventFetcher {
+	return func(ctx context.Context, input *cloudtrail.LookupEventsInput, maxResults int) ([]ctypes.Event, error) {
+		if len(input.LookupAttributes) != 1 {
+			return nil, fmt.Errorf("unexpected lookup attributes: %+v", input.LookupAttributes)
+		}
+		value := aws.ToString(input.LookupAttributes[0].AttributeValue)
+		if lookups != nil {
+			*lookups = append(*lookups, string(input.LookupAttributes[0].AttributeKey)+"="+value)
+		}
+		events := byValue[value]
+		if len(events) > maxResults {
+			events = events[:maxResults]
+		}
+		return events, nil
+	}
+}
+
+func TestTraceCredentialChain(t *testing.T) {
+	fetch := fakeFetcher(map[string][]ctypes.Event{
+		"AssumeRole": {
+			{CloudTrailEvent: aws.String(`{
+				"eventID": "unrelated",
+				"eventName": "AssumeRole",
+				"eventTime": "2025-05-12T00:40:00Z",
+				"responseElements": {"credentials": {"accessKeyId": "ASIAOTHERKEY00000000"}}
+			}`)},
+			{CloudTrailEvent: aws.String(`{
+				"eventID": "hop-2",
+				"eventName": "AssumeRole",
+				"eventTime": "2025-05-12T00:30:00Z",
+				"sourceIPAddress": "198.51.100.7",
+				"userIdentity": {
+					"type": "AssumedRole",
+					"arn": "arn:aws:sts::123456789012:assumed-role/Jump/alice",
+					"accessKeyId": "ASIAJUMPKEY000000000"
+				},
+				"requestParameters": {"roleArn": "arn:aws:iam::123456789012:role/Admin"},
+				"responseElements": {"credentials": {"accessKeyId": "ASIATARGETKEY0000000"}}
+			}`)},
+		},
+		"GetSessionToken": {
+			{CloudTrailEvent: aws.String(`{
+				"eventID": "hop-1",
+				"eventName": "GetSessionToken",
+				"eventTime": "2025-05-12T00:10:00Z",
+				"userIdentity": {
+					"type": "IAMUser",
+					"userName": "alice",
+					"arn": "arn:aws:iam::123456789012:user/alice",
+					"accessKeyId": "AKIAALICEKEY00000000"
+				},
+				"responseElements": {"credentials": {"accessKeyId": "ASIAJUMPKEY000000000"}}
+			}`)},
+		},
+	}, nil)
+
+	usedAt := time.Date(2025, 5, 12, 1, 0, 0, 0, time.UTC)
+	search := newIssuerSearch(fetch, 100)
+	chain, err := traceCredentialChain(context.Background(), search, "ASIATARGETKEY0000000", usedAt)
+	if err != nil {
+		t.Fatalf("traceCredentialChain() failed: %v", err)
+	}
+
+	if len(chain) != 2 {
+		t.Fatalf("traceCredentialChain() returned %d hops, want 2", len(chain))
+	}
+	if chain[0].Issuer.EventId != "hop-2" || chain[1].Issuer.EventId != "hop-1" {
+		t.Errorf("unexpected chain order: %s, %s", chain[0].Issuer.EventId, chain[1].Issuer.EventId)
+	}
+	if got := getGrantedIdentity(chain[0].Issuer); got != "arn:aws:iam::123456789012:role/Admin" {
+		t.Errorf("getGrantedIdentity() = %q, want role ARN", got)
+	}
+}
+
+func TestTraceCredentialChainNoIssuer(t *testing.T) {
+	search := newIssuerSearch(fakeFetcher(nil, nil), 100)
+	chain, err := traceCredentialChain(context.Background(), search, "ASIAUNKNOWN000000000", time.Now())
+	if err != nil {
+		t.Fatalf("traceCredentialChain() failed: %v", err)
+	}
+	if len(chain) != 0 {
+		t.Errorf("traceCredentialChain() returned %d hops, want 0", len(chain))
+	}
+	if search.truncated {
+		t.Error("expected an exhaustive search")
+	}
+}
+
+func TestTraceCredentialChainByResourceName(t *testing.T) {
+	var lookups []string
+	fetch := fakeFetcher(map[string][]ctypes.Event{
+		"ASIATARGETKEY0000000": {
+			{CloudTrailEvent: aws.String(`{
+				"eventID": "issuer",
+				"eventName": "AssumeRole",
+				"eventTime": "2025-05-12T00:30:00Z",
+				"userIdentity": {"type": "IAMUser", "accessKeyId": "AKIAALICEKEY00000000"},
+				"responseElements": {"credentials": {"accessKeyId": "ASIATARGETKEY0000000"}}
+			}`)},
+		},
+	}, &lookups)
+
+	usedAt := time.Date(2025, 5, 12, 1, 0, 0, 0, time.UTC)
+	chain, err := traceCredentialChain(context.Background(), newIssuerSearch(fetch, 100), "ASIATARGETKEY0000000", usedAt)
+	if err != nil {
+		t.Fatalf("traceCredentialChain() failed: %v", err)
+	}
+	if len(chain) != 1 || chain[0].Issuer.EventId != "issuer" {
+		t.Fatalf("traceCredentialChain() = %+v, want the issuer", chain)
+	}
+	if !reflect.DeepEqual(lookups, []string{"ResourceName=ASIATARGETKEY0000000"}) {
+		t.Errorf("expected a single lookup by resource name, got %v", lookups)
+	}
+}
+
+func TestTraceCredentialChainTruncated(t *testing.T) {
+	// The unrelated AssumeRole events use up the budget before the issuer is reached
+	var events []ctypes.Event
+	for n := 0; n < 5; n++ {
+		events = append(events, ctypes.Event{CloudTrailEvent: aws.String(fmt.Sprintf(`{
+			"eventName": "AssumeRole",
+			"responseElements": {"credentials": {"accessKeyId": "ASIAOTHERKEY0000000%d"}}
+		}`, n))})
+	}
+	fetch := fakeFetcher(map[string][]ctypes.Event{"AssumeRole": events}, nil)
+
+	search := newIssuerSearch(fetch, 3)
+	chain, err := traceCredentialChain(context.Background(), search, "ASIATARGETKEY0000000", time.Now())
+	if err != nil {
+		t.Fatalf("traceCredentialChain() failed: %v", err)
+	}
+	if len(chain) != 0 {
+		t.Errorf("traceCredentialChain() returned %d hops, want 0", len(chain))
+	}
+	if !search.truncated {
+		t.Error("expected the search to be reported as truncated")
+	}
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/guessi/cloudtrail-cli/pkg/accounts"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// JSON token colors of the detail view
+var (
+	jsonKeyColor     = text.Colors{text.FgCyan}
+	jsonStringColor  = text.Colors{text.FgGreen}
+	jsonNumberColor  = text.Colors{text.FgYellow}
+	jsonLiteralColor = text.Colors{text.FgMagenta}
+)
+
+// colorEnabled reports whether the output is an interactive terminal that accepts colors
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatJSON renders a value as indented JSON with sorted keys, optionally syntax-colored
+func formatJSON(v interface{}, color bool) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	// Decode again so numbers keep their original representation
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	writeJSONValue(&b, value, "", color)
+	return b.String(), nil
+}
+
+// writeJSONValue writes one JSON value at the given indentation
+func writeJSONValue(b *strings.Builder, v interface{}, indent string, color bool) {
+	paint := func(colors text.Colors, s string) string {
+		if !color {
+			return s
+		}
+		return colors.Sprint(s)
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("{\n")
+		for idx, key := range keys {
+			quoted, _ := json.Marshal(key)
+			b.WriteString(indent + "  " + paint(jsonKeyColor, string(quoted)) + ": ")
+			writeJSONValue(b, value[key], indent+"  ", color)
+			if idx < len(keys)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for idx, item := range value {
+			b.WriteString(indent + "  ")
+			writeJSONValue(b, item, indent+"  ", color)
+			if idx < len(value)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	case string:
+		quoted, _ := json.Marshal(value)
+		b.WriteString(paint(jsonStringColor, string(quoted)))
+	case json.Number:
+		b.WriteString(paint(jsonNumberColor, value.String()))
+	case bool:
+		b.WriteString(paint(jsonLiteralColor, fmt.Sprint(value)))
+	case nil:
+		b.WriteString(paint(jsonLiteralColor, "null"))
+	}
+}
+
+// renderKeyValues renders a titled two-column panel, skipping empty values
+func renderKeyValues(w io.Writer, title string, pairs [][2]string) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle(title)
+
+	for _, pair := range pairs {
+		if pair[1] == "" {
+			continue
+		}
+		t.AppendRow(table.Row{pair[0], pair[1]})
+	}
+	if t.Length() == 0 {
+		return
+	}
+	t.Render()
+}
+
+// renderJSONSection prints a titled JSON document unless it is empty
+func renderJSONSection(w io.Writer, title string, v interface{}, color bool) error {
+	if types.IsNull(v) {
+		return nil
+	}
+	output, err := formatJSON(v, color)
+	if err != nil {
+		return fmt.Errorf("unable to render %s: %w", strings.ToLower(title), err)
+	}
+	fmt.Fprintf(w, "\n%s\n%s\n", title, output)
+	return nil
+}
+
+// renderAccountAliases lists the aliases of the accounts mentioned in an event
+func renderAccountAliases(w io.Writer, aliases *accounts.Aliases, data string) {
+	var pairs [][2]string
+	for _, id := range aliases.Mentioned(data) {
+		pairs = append(pairs, [2]string{id, aliases.Name(id)})
+	}
+	renderKeyValues(w, "Accounts", pairs)
+}
+
+// renderEventDetail renders every part of a single event
+func renderEventDetail(w io.Writer, e *types.Event, color bool) error {
+	r := e.Record
+	u := r.UserIdentity
+
+	renderKeyValues(w, "Event", [][2]string{
+		{"EventId", r.EventId},
+		{"EventName", r.EventName},
+		{"EventTime", r.EventTime},
+		{"EventSource", r.EventSource},
+		{"EventType", r.EventType},
+		{"EventCategory", r.EventCategory},
+		{"AwsRegion", r.AwsRegion},
+		{"SourceIPAddress", r.SourceIPAddress},
+		{"UserAgent", r.UserAgent},
+		{"ReadOnly", formatOptionalBool(r.ReadOnly)},
+		{"ManagementEvent", formatOptionalBool(r.ManagementEvent)},
+		{"RecipientAccountId", r.RecipientAccountId},
+		{"RequestId", r.RequestId},
+		{"SharedEventId", r.SharedEventId},
+		{"VpcEndpointId", r.VpcEndpointId},
+	})
+
+	var onBehalfOf string
+	if u.OnBehalfOf != nil {
+		onBehalfOf = u.OnBehalfOf.UserId
+	}
+	renderKeyValues(w, "Identity", [][2]string{
+		{"Name", getDisplayUserName(u)},
+		{"Type", u.Type},
+		{"Arn", u.Arn},
+		{"PrincipalId", u.PrincipalId},
+		{"AccountId", u.AccountId},
+		{"AccessKeyId", u.AccessKeyId},
+		{"UserName", u.UserName},
+		{"InvokedBy", u.InvokedBy},
+		{"IdentityProvider", u.IdentityProvider},
+		{"CredentialId", u.CredentialId},
+		{"OnBehalfOf", onBehalfOf},
+		{"SessionIssuer", u.SessionContext.SessionIssuer.Arn},
+		{"SourceIdentity", u.SessionContext.SourceIdentity},
+		{"SessionCreated", u.SessionContext.Attributes.CreationDate},
+		{"MfaAuthenticated", u.SessionContext.Attributes.MfaAuthenticated},
+		{"FederatedProvider", u.SessionContext.WebIdFederationData.FederatedProvider},
+	})
+
+	if r.ErrorCode != "" || r.ErrorMessage != "" {
+		renderKeyValues(w, "Error", [][2]string{
+			{"ErrorCode", r.ErrorCode},
+			{"ErrorMessage", r.ErrorMessage},
+		})
+	}
+
+	if r.TLSDetails != nil {
+		renderKeyValues(w, "TLS", [][2]string{
+			{"TLSVersion", r.TLSDetails.TLSVersion},
+			{"CipherSuite", r.TLSDetails.CipherSuite},
+			{"ClientProvidedHostHeader", r.TLSDetails.ClientProvidedHostHeader},
+		})
+	}
+
+	if resources := eventResources(e); len(resources) > 0 {
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetTitle("Resources")
+		t.AppendHeader(table.Row{"ResourceType", "ResourceName"})
+		for _, resource := range resources {
+			t.AppendRow(table.Row{resource.ResourceType, resource.ResourceName})
+		}
+		t.Style().Format.Header = text.FormatDefault
+		t.Render()
+	}
+
+	if err := renderJSONSection(w, "Request parameters", r.RequestParameters, color); err != nil {
+		return err
+	}
+	if err := renderJSONSection(w, "Response elements", r.ResponseElements, color); err != nil {
+		return err
+	}
+	if r.AdditionalEventData != nil {
+		if err := renderJSONSection(w, "Additional event data", r.AdditionalEventData, color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showHandlerWithLookup allows injection of LookupEvents function for testing
+func showHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if i.EventId == "" {
+		return fmt.Errorf("an event id is required, e.g. 'show <event-id>'")
+	}
+	if err := validateInput(i); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	// Search the whole event history unless a window was given
+	if i.StartTime.IsZero() {
+		setDefaultTimeRange(&i)
+		i.StartTime = i.EndTime.Add(-constants.EventHistoryRetention)
+	}
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	input, err := buildCloudTrailInput(i)
+	if err != nil {
+		return err
+	}
+
+	events, err := lookupFunc(ctx, svc, input, 1)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+
+	for _, event := range events {
+		cloudTrailEvent, err := parseCloudTrailEvent(event)
+		if err != nil {
+			continue
+		}
+		e, err := redactEvent(i.Redactor, newEvent(event, cloudTrailEvent))
+		if err != nil {
+			return err
+		}
+		if err := renderEventDetail(os.Stdout, e, colorEnabled(os.Stdout)); err != nil {
+			return err
+		}
+		// Aliases identify accounts, so they are left out when redacting
+		if i.Redactor == nil {
+			renderAccountAliases(os.Stdout, i.Accounts, aws.ToString(event.CloudTrailEvent))
+		}
+		return nil
+	}
+
+	fmt.Printf("No event found with id %s between %s and %s\n", i.EventId, i.StartTime.Format(time.RFC3339), i.EndTime.Format(time.RFC3339))
+	return nil
+}
+
+// ShowHandler renders a single event in full
+func ShowHandler(i types.CloudTrailCliInput) error {
+	return showHandlerWithLookup(i, LookupEvents)
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+func TestFormatJSON(t *testing.T) {
+	value := map[string]interface{}{
+		"bucketName": "reports",
+		"maxKeys":    1000,
+		"versioned":  true,
+		"tags":       []interface{}{},
+		"owner":      nil,
+	}
+
+	plain, err := formatJSON(value, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "bucketName": "reports",
+  "maxKeys": 1000,
+  "owner": null,
+  "tags": [],
+  "versioned": true
+}`
+	if plain != expected {
+		t.Errorf("Unexpected plain output:\n%s", plain)
+	}
+
+	colored, err := formatJSON(value, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(colored, jsonKeyColor.Sprint(`"bucketName"`)) {
+		t.Error("Expected keys to be colored")
+	}
+	if !strings.Contains(colored, jsonStringColor.Sprint(`"reports"`)) {
+		t.Error("Expected string values to be colored")
+	}
+	if !strings.Contains(colored, jsonNumberColor.Sprint("1000")) {
+		t.Error("Expected numbers to be colored")
+	}
+}
+
+func TestRenderEventDetail(t *testing.T) {
+	event := ctypes.Event{
+		CloudTrailEvent: aws.String(`{
+			"eventID": "detail-event",
+			"eventName": "PutBucketPolicy",
+			"eventSource": "s3.amazonaws.com",
+			"userIdentity": {
+				"type": "AssumedRole",
+				"arn": "arn:aws:sts::123456789012:assumed-role/Admin/alice",
+				"sessionContext": {"attributes": {"mfaAuthenticated": "true"}}
+			},
+			"errorCode": "AccessDenied",
+			"errorMessage": "Access Denied",
+			"requestParameters": {"bucketName": "reports"},
+			"tlsDetails": {"tlsVersion": "TLSv1.3", "cipherSuite": "TLS_AES_128_GCM_SHA256"}
+		}`),
+		Resources: []ctypes.Resource{{ResourceType: aws.String("AWS::S3::Bucket"), ResourceName: aws.String("reports")}},
+	}
+
+	record, err := parseCloudTrailEvent(event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := renderEventDetail(&b, newEvent(event, record), false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output := b.String()
+	for _, expected := range []string{
+		"detail-event",
+		"MfaAuthenticated",
+		"Access Denied",
+		"TLSv1.3",
+		"AWS::S3::Bucket",
+		"Request parameters",
+		`"bucketName": "reports"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+	if strings.Contains(output, "Response elements") {
+		t.Error("Expected empty response elements to be omitted")
+	}
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/sigma"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// decodeRecords decodes raw CloudTrail records into generic JSON objects
+func decodeRecords(raw []json.RawMessage) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0, len(raw))
+	for _, data := range raw {
+		var record map[string]interface{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("Failed to parse event JSON: skipping event")
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// loadEventRecords retrieves raw records from offline log files when given,
+// from LookupEvents otherwise
+func loadEventRecords(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) ([]json.RawMessage, error) {
+	if len(i.EventFiles) > 0 {
+		return readEventFiles(i.EventFiles)
+	}
+
+	if err := validateInput(i); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return nil, err
+	}
+
+	input, err := buildCloudTrailInput(i)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := lookupFunc(ctx, svc, input, i.MaxResults)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+	}
+
+	raw := make([]json.RawMessage, 0, len(events))
+	for _, event := range events {
+		if event.CloudTrailEvent == nil || len(*event.CloudTrailEvent) > constants.MaxJSONPayloadSize {
+			continue
+		}
+		raw = append(raw, json.RawMessage(*event.CloudTrailEvent))
+	}
+	return raw, nil
+}
+
+// renderSigmaMatches creates and renders the matched Sigma rules table
+func renderSigmaMatches(matches []sigma.Match) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Level", "Title", "RuleId", "Matches", "EventIds", "EventNames"})
+
+	for _, match := range matches {
+		var ids, names []string
+		for _, event := range match.Events {
+			ids = append(ids, types.LookupString(event, "eventID"))
+			name := types.LookupString(event, "eventName")
+			if !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+		t.AppendRow(table.Row{
+			match.Rule.Level, match.Rule.Title, match.Rule.Id, len(match.Events),
+			strings.Join(ids, "\n"), strings.Join(names, "\n"),
+		})
+	}
+
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+}
+
+// sigmaHandlerWithLookup allows injection of LookupEvents function for testing
+func sigmaHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if len(i.SigmaRules) == 0 {
+		return fmt.Errorf("--rules is required to evaluate Sigma rules")
+	}
+
+	rules, skipped, err := sigma.LoadRules(i.SigmaRules)
+	if err != nil {
+		return err
+	}
+	for _, rule := range skipped {
+		log.Printf("Skipping rule in %s: %s", rule.Path, rule.Reason)
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("no Sigma rules with the aws/cloudtrail logsource found")
+	}
+
+	raw, err := loadEventRecords(i, lookupFunc)
+	if err != nil {
+		return err
+	}
+
+	renderSigmaMatches(sigma.Evaluate(rules, decodeRecords(raw)))
+	return nil
+}
+
+// SigmaHandler evaluates Sigma rules against events from LookupEvents or offline log files
+func SigmaHandler(i types.CloudTrailCliInput) error {
+	return sigmaHandlerWithLookup(i, LookupEvents)
+}
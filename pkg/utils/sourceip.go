@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// ipLike matches values made of IP address characters only, which are
+// rejected instead of being taken for host names when they do not parse
+var ipLike = regexp.MustCompile(`^[0-9A-Fa-f:.]*$`)
+
+// sourceIPMatcher matches a source IP address against a CIDR block, or a
+// non-IP value such as a service principal against a host name pattern
+type sourceIPMatcher struct {
+	prefix  netip.Prefix
+	pattern *regexp.Regexp
+}
+
+// matches reports whether the value is in the block or matches the pattern
+func (m sourceIPMatcher) matches(value string) bool {
+	if m.pattern != nil {
+		return m.pattern.MatchString(value)
+	}
+	ip, err := netip.ParseAddr(value)
+	if err != nil {
+		return false
+	}
+	return m.prefix.Contains(ip.WithZone("").Unmap())
+}
+
+// sourceIPFilter keeps the events whose SourceIPAddress matches any included
+// entry, if there is one, and none of the excluded entries
+type sourceIPFilter struct {
+	include []sourceIPMatcher
+	exclude []sourceIPMatcher
+}
+
+// parseSourceIPMatcher accepts an IP address, a CIDR block or a host name
+// pattern with "*" wildcards
+func parseSourceIPMatcher(entry string) (sourceIPMatcher, error) {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return sourceIPMatcher{prefix: prefix.Masked()}, nil
+	}
+	if ip, err := netip.ParseAddr(entry); err == nil {
+		ip = ip.WithZone("").Unmap()
+		return sourceIPMatcher{prefix: netip.PrefixFrom(ip, ip.BitLen())}, nil
+	}
+	if strings.Contains(entry, "/") || ipLike.MatchString(entry) {
+		return sourceIPMatcher{}, fmt.Errorf("invalid --source-ip %q: not an IP address or CIDR block", entry)
+	}
+	return sourceIPMatcher{pattern: wildcardPattern(entry)}, nil
+}
+
+// parseSourceIPFilter parses --source-ip values. Each value is a
+// comma-separated list whose entries are negated with a leading "!".
+func parseSourceIPFilter(values []string) (*sourceIPFilter, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	filter := &sourceIPFilter{}
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			negate := strings.HasPrefix(entry, "!")
+			entry = strings.TrimSpace(strings.TrimPrefix(entry, "!"))
+			if entry == "" {
+				return nil, fmt.Errorf("invalid --source-ip %q: empty entry", value)
+			}
+
+			matcher, err := parseSourceIPMatcher(entry)
+			if err != nil {
+				return nil, err
+			}
+			if negate {
+				filter.exclude = append(filter.exclude, matcher)
+			} else {
+				filter.include = append(filter.include, matcher)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// matches reports whether a SourceIPAddress passes the filter. A nil filter
+// keeps everything.
+func (f *sourceIPFilter) matches(value string) bool {
+	if f == nil {
+		return true
+	}
+	for _, m := range f.exclude {
+		if m.matches(value) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, m := range f.include {
+		if m.matches(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseSourceIPFilter(t *testing.T) {
+	testCases := []struct {
+		name        string
+		values      []string
+		expectError bool
+	}{
+		{name: "Single IPv4 address", values: []string{"203.0.113.10"}},
+		{name: "CIDR list", values: []string{"10.0.0.0/8, 2001:db8::/32"}},
+		{name: "Negation", values: []string{"!10.0.0.0/8", "! 192.168.0.0/16"}},
+		{name: "Host name pattern", values: []string{"*.amazonaws.com"}},
+		{name: "Invalid prefix length", values: []string{"10.0.0.0/33"}, expectError: true},
+		{name: "Invalid IPv4 address", values: []string{"10.0.0.256"}, expectError: true},
+		{name: "Invalid IPv6 address", values: []string{"2001:db8:::1"}, expectError: true},
+		{name: "Empty entry", values: []string{"10.0.0.1,,10.0.0.2"}, expectError: true},
+		{name: "Negation only", values: []string{"!"}, expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseSourceIPFilter(tc.values)
+			if (err != nil) != tc.expectError {
+				t.Errorf("parseSourceIPFilter() error = %v, expectError %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestSourceIPFilterMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []string
+		address  string
+		expected bool
+	}{
+		{name: "No filter", values: nil, address: "203.0.113.10", expected: true},
+		{name: "Same IPv4 address", values: []string{"203.0.113.10"}, address: "203.0.113.10", expected: true},
+		{name: "Other IPv4 address", values: []string{"203.0.113.10"}, address: "203.0.113.11", expected: false},
+		{name: "IPv4 address in block", values: []string{"203.0.113.0/24"}, address: "203.0.113.200", expected: true},
+		{name: "Unmasked block", values: []string{"203.0.113.77/24"}, address: "203.0.113.1", expected: true},
+		{name: "IPv4 address outside block", values: []string{"203.0.113.0/24"}, address: "203.0.114.1", expected: false},
+		{name: "Second block of a list", values: []string{"10.0.0.0/8,203.0.113.0/24"}, address: "203.0.113.1", expected: true},
+		{name: "Repeated flag", values: []string{"10.0.0.0/8", "203.0.113.0/24"}, address: "10.1.2.3", expected: true},
+		{name: "IPv6 address in block", values: []string{"2001:db8::/32"}, address: "2001:db8:1::5", expected: true},
+		{name: "IPv6 address outside block", values: []string{"2001:db8::/32"}, address: "2001:db9::5", expected: false},
+		{name: "IPv6 address in IPv4 block", values: []string{"0.0.0.0/0"}, address: "2001:db8::5", expected: false},
+		{name: "IPv4-mapped IPv6 address", values: []string{"203.0.113.0/24"}, address: "::ffff:203.0.113.9", expected: true},
+		{name: "Service principal with a block", values: []string{"0.0.0.0/0"}, address: "eks.amazonaws.com", expected: false},
+		{name: "AWS Internal with a block", values: []string{"0.0.0.0/0", "::/0"}, address: "AWS Internal", expected: false},
+		{name: "Service principal pattern", values: []string{"*.amazonaws.com"}, address: "EKS.amazonaws.com", expected: true},
+		{name: "Pattern on an IP address", values: []string{"*.amazonaws.com"}, address: "203.0.113.10", expected: false},
+		{name: "Negated block", values: []string{"!10.0.0.0/8"}, address: "10.1.2.3", expected: false},
+		{name: "Outside negated block", values: []string{"!10.0.0.0/8"}, address: "203.0.113.10", expected: true},
+		{name: "Service principal outside negated block", values: []string{"!10.0.0.0/8"}, address: "eks.amazonaws.com", expected: true},
+		{name: "Negation wins", values: []string{"10.0.0.0/8,!10.1.0.0/16"}, address: "10.1.2.3", expected: false},
+		{name: "Included and not negated", values: []string{"10.0.0.0/8,!10.1.0.0/16"}, address: "10.2.0.1", expected: true},
+		{name: "Negated service principals", values: []string{"!*.amazonaws.com"}, address: "eks.amazonaws.com", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := parseSourceIPFilter(tc.values)
+			if err != nil {
+				t.Fatalf("parseSourceIPFilter() failed: %v", err)
+			}
+			if got := filter.matches(tc.address); got != tc.expected {
+				t.Errorf("matches(%q) = %v, want %v", tc.address, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// trailsAPI is the subset of the CloudTrail client used to inspect trails and event data stores
+type trailsAPI interface {
+	DescribeTrails(ctx context.Context, params *cloudtrail.DescribeTrailsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DescribeTrailsOutput, error)
+	GetTrailStatus(ctx context.Context, params *cloudtrail.GetTrailStatusInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailStatusOutput, error)
+	GetEventSelectors(ctx context.Context, params *cloudtrail.GetEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error)
+	ListEventDataStores(ctx context.Context, params *cloudtrail.ListEventDataStoresInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.ListEventDataStoresOutput, error)
+	GetEventDataStore(ctx context.Context, params *cloudtrail.GetEventDataStoreInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventDataStoreOutput, error)
+}
+
+// trailCoverage describes the regions a trail records events in
+func trailCoverage(trail ctypes.Trail) string {
+	if aws.ToBool(trail.IsMultiRegionTrail) {
+		return "all regions"
+	}
+	return aws.ToString(trail.HomeRegion)
+}
+
+// formatTime renders an optional timestamp
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatLogging renders the logging state of a trail
+func formatLogging(status *cloudtrail.GetTrailStatusOutput) string {
+	if status == nil {
+		return "unknown"
+	}
+	if aws.ToBool(status.IsLogging) {
+		return "on"
+	}
+	return "off"
+}
+
+// formatStoreStatus renders the status of an event data store
+func formatStoreStatus(status ctypes.EventDataStoreStatus) string {
+	if status == "" {
+		return "unknown"
+	}
+	return string(status)
+}
+
+// describeEventSelector summarizes a basic event selector
+func describeEventSelector(selector ctypes.EventSelector) []string {
+	readWrite := string(selector.ReadWriteType)
+	if readWrite == "" {
+		readWrite = string(ctypes.ReadWriteTypeAll)
+	}
+
+	var lines []string
+	// Management events are included unless explicitly disabled
+	if selector.IncludeManagementEvents == nil || *selector.IncludeManagementEvents {
+		line := "Management events: " + readWrite
+		if len(selector.ExcludeManagementEventSources) > 0 {
+			line += " (excluding " + strings.Join(selector.ExcludeManagementEventSources, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	for _, resource := range selector.DataResources {
+		lines = append(lines, fmt.Sprintf("Data events: %s %s (%s)",
+			aws.ToString(resource.Type), strings.Join(resource.Values, ", "), readWrite))
+	}
+	return lines
+}
+
+// describeAdvancedEventSelector summarizes an advanced event selector as its field conditions
+func describeAdvancedEventSelector(selector ctypes.AdvancedEventSelector) string {
+	var conditions []string
+	for _, field := range selector.FieldSelectors {
+		name := aws.ToString(field.Field)
+		operators := []struct {
+			operator string
+			values   []string
+		}{
+			{"=", field.Equals},
+			{"!=", field.NotEquals},
+			{"starts with", field.StartsWith},
+			{"does not start with", field.NotStartsWith},
+			{"ends with", field.EndsWith},
+			{"does not end with", field.NotEndsWith},
+		}
+		for _, o := range operators {
+			if len(o.values) > 0 {
+				conditions = append(conditions, fmt.Sprintf("%s %s %s", name, o.operator, strings.Join(o.values, ", ")))
+			}
+		}
+	}
+
+	summary := strings.Join(conditions, "; ")
+	if name := aws.ToString(selector.Name); name != "" {
+		summary = name + ": " + summary
+	}
+	return summary
+}
+
+// describeSelectors summarizes which events a trail or event data store records
+func describeSelectors(selectors []ctypes.EventSelector, advanced []ctypes.AdvancedEventSelector) []string {
+	var lines []string
+	for _, selector := range selectors {
+		lines = append(lines, describeEventSelector(selector)...)
+	}
+	for _, selector := range advanced {
+		lines = append(lines, describeAdvancedEventSelector(selector))
+	}
+	return lines
+}
+
+// listEventDataStores pages through the event data stores of the account and
+// describes each of them. ListEventDataStores only reliably returns their name
+// and ARN, the other fields being deprecated there.
+func listEventDataStores(ctx context.Context, api trailsAPI) ([]*cloudtrail.GetEventDataStoreOutput, error) {
+	var listed []ctypes.EventDataStore
+	input := &cloudtrail.ListEventDataStoresInput{}
+	for {
+		out, err := api.ListEventDataStores(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list event data stores: %w", err)
+		}
+		listed = append(listed, out.EventDataStores...)
+		if aws.ToString(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	stores := make([]*cloudtrail.GetEventDataStoreOutput, 0, len(listed))
+	for _, store := range listed {
+		out, err := api.GetEventDataStore(ctx, &cloudtrail.GetEventDataStoreInput{EventDataStore: store.EventDataStoreArn})
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe event data store %s: %w", aws.ToString(store.Name), err)
+		}
+		stores = append(stores, out)
+	}
+	return stores, nil
+}
+
+// describeTrails returns the trails, optionally only the one matching a name or ARN
+func describeTrails(ctx context.Context, api trailsAPI, name string) ([]ctypes.Trail, error) {
+	out, err := api.DescribeTrails(ctx, &cloudtrail.DescribeTrailsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe trails: %w", err)
+	}
+	if name == "" {
+		return out.TrailList, nil
+	}
+	for _, trail := range out.TrailList {
+		if aws.ToString(trail.Name) == name || aws.ToString(trail.TrailARN) == name {
+			return []ctypes.Trail{trail}, nil
+		}
+	}
+	return nil, fmt.Errorf("trail %q not found", name)
+}
+
+// getTrailStatus returns the logging status of a trail, addressed by ARN so
+// trails replicated from other regions resolve as well
+func getTrailStatus(ctx context.Context, api trailsAPI, trail ctypes.Trail) (*cloudtrail.GetTrailStatusOutput, error) {
+	status, err := api.GetTrailStatus(ctx, &cloudtrail.GetTrailStatusInput{Name: trail.TrailARN})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get status of trail %s: %w", aws.ToString(trail.Name), err)
+	}
+	return status, nil
+}
+
+// renderTrailList prints an overview of the trails and event data stores
+func renderTrailList(ctx context.Context, api trailsAPI, w io.Writer, config types.CloudTrailCliInput) error {
+	trails, err := describeTrails(ctx, api, "")
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Trails")
+	t.AppendHeader(table.Row{"Name", "HomeRegion", "Regions", "Organization", "Logging", "S3Bucket", "LogFileValidation"})
+	var rows []table.Row
+	for _, trail := range trails {
+		// A trail whose status cannot be read, such as an organization trail
+		// seen from a member account, is listed with an unknown logging state
+		status, _ := getTrailStatus(ctx, api, trail)
+		rows = append(rows, table.Row{
+			aws.ToString(trail.Name),
+			aws.ToString(trail.HomeRegion),
+			trailCoverage(trail),
+			aws.ToBool(trail.IsOrganizationTrail),
+			formatLogging(status),
+			aws.ToString(trail.S3BucketName),
+			aws.ToBool(trail.LogFileValidationEnabled),
+		})
+	}
+	t.AppendRows(displayRows(config, rows))
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+
+	stores, err := listEventDataStores(ctx, api)
+	if err != nil {
+		return err
+	}
+
+	t = table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Event data stores")
+	t.AppendHeader(table.Row{"Name", "Status", "Regions", "Organization", "RetentionDays", "EventDataStoreArn"})
+	rows = nil
+	for _, store := range stores {
+		regions := "single region"
+		if aws.ToBool(store.MultiRegionEnabled) {
+			regions = "all regions"
+		}
+		rows = append(rows, table.Row{
+			aws.ToString(store.Name),
+			formatStoreStatus(store.Status),
+			regions,
+			aws.ToBool(store.OrganizationEnabled),
+			aws.ToInt32(store.RetentionPeriod),
+			aws.ToString(store.EventDataStoreArn),
+		})
+	}
+	t.AppendRows(displayRows(config, rows))
+	t.Style().Format.Header = text.FormatDefault
+	t.Render()
+	return nil
+}
+
+// renderTrailStatus prints the delivery status and event selection of trails
+func renderTrailStatus(ctx context.Context, api trailsAPI, w io.Writer, name string, config types.CloudTrailCliInput) error {
+	trails, err := describeTrails(ctx, api, name)
+	if err != nil {
+		return err
+	}
+
+	for _, trail := range trails {
+		// A status that cannot be read is reported in place of the delivery
+		// details, and the other trails are still shown
+		status, statusErr := getTrailStatus(ctx, api, trail)
+		selectors, err := api.GetEventSelectors(ctx, &cloudtrail.GetEventSelectorsInput{TrailName: trail.TrailARN})
+		if err != nil {
+			return fmt.Errorf("unable to get event selectors of trail %s: %w", aws.ToString(trail.Name), err)
+		}
+
+		pairs := [][2]string{
+			{"TrailARN", aws.ToString(trail.TrailARN)},
+			{"Regions", trailCoverage(trail)},
+			{"GlobalServiceEvents", strconv.FormatBool(aws.ToBool(trail.IncludeGlobalServiceEvents))},
+			{"Organization", strconv.FormatBool(aws.ToBool(trail.IsOrganizationTrail))},
+			{"Logging", formatLogging(status)},
+		}
+		if status != nil {
+			pairs = append(pairs, [][2]string{
+				{"StartLoggingTime", formatTime(status.StartLoggingTime)},
+				{"StopLoggingTime", formatTime(status.StopLoggingTime)},
+				{"LatestDeliveryTime", formatTime(status.LatestDeliveryTime)},
+				{"LatestDeliveryError", aws.ToString(status.LatestDeliveryError)},
+				{"LatestDigestDeliveryError", aws.ToString(status.LatestDigestDeliveryError)},
+				{"LatestNotificationError", aws.ToString(status.LatestNotificationError)},
+				{"LatestCloudWatchLogsDeliveryError", aws.ToString(status.LatestCloudWatchLogsDeliveryError)},
+			}...)
+		} else {
+			pairs = append(pairs, [2]string{"StatusError", statusErr.Error()})
+		}
+		pairs = append(pairs, [2]string{"EventSelection", strings.Join(describeSelectors(selectors.EventSelectors, selectors.AdvancedEventSelectors), "\n")})
+		renderKeyValues(w, "Trail "+aws.ToString(trail.Name), displayPairs(config, pairs))
+	}
+
+	if name != "" {
+		return nil
+	}
+
+	stores, err := listEventDataStores(ctx, api)
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		renderKeyValues(w, "Event data store "+aws.ToString(store.Name), displayPairs(config, [][2]string{
+			{"EventDataStoreArn", aws.ToString(store.EventDataStoreArn)},
+			{"Status", formatStoreStatus(store.Status)},
+			{"MultiRegion", strconv.FormatBool(aws.ToBool(store.MultiRegionEnabled))},
+			{"EventSelection", strings.Join(describeSelectors(nil, store.AdvancedEventSelectors), "\n")},
+		}))
+	}
+	return nil
+}
+
+// TrailsListHandler lists the trails and event data stores of the account
+func TrailsListHandler(i types.CloudTrailCliInput) error {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+	return renderTrailList(ctx, svc, os.Stdout, i)
+}
+
+// TrailsStatusHandler shows whether trails are logging and which events they record
+func TrailsStatusHandler(i types.CloudTrailCliInput, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+	return renderTrailStatus(ctx, svc, os.Stdout, name, i)
+}
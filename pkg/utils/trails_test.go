@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// mockTrailsAPI serves canned trail and event data store descriptions
+type mockTrailsAPI struct {
+	trails    []ctypes.Trail
+	statuses  map[string]*cloudtrail.GetTrailStatusOutput
+	selectors map[string]*cloudtrail.GetEventSelectorsOutput
+	stores    [][]ctypes.EventDataStore
+	details   map[string]*cloudtrail.GetEventDataStoreOutput
+	listCalls int
+}
+
+func (m *mockTrailsAPI) DescribeTrails(ctx context.Context, params *cloudtrail.DescribeTrailsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DescribeTrailsOutput, error) {
+	return &cloudtrail.DescribeTrailsOutput{TrailList: m.trails}, nil
+}
+
+func (m *mockTrailsAPI) GetTrailStatus(ctx context.Context, params *cloudtrail.GetTrailStatusInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailStatusOutput, error) {
+	status, ok := m.statuses[aws.ToString(params.Name)]
+	if !ok {
+		return nil, errors.New("TrailNotFoundException")
+	}
+	return status, nil
+}
+
+func (m *mockTrailsAPI) GetEventSelectors(ctx context.Context, params *cloudtrail.GetEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error) {
+	return m.selectors[aws.ToString(params.TrailName)], nil
+}
+
+func (m *mockTrailsAPI) ListEventDataStores(ctx context.Context, params *cloudtrail.ListEventDataStoresInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.ListEventDataStoresOutput, error) {
+	page := m.stores[m.listCalls]
+	m.listCalls++
+	out := &cloudtrail.ListEventDataStoresOutput{EventDataStores: page}
+	if m.listCalls < len(m.stores) {
+		out.NextToken = aws.String("next")
+	}
+	return out, nil
+}
+
+func (m *mockTrailsAPI) GetEventDataStore(ctx context.Context, params *cloudtrail.GetEventDataStoreInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventDataStoreOutput, error) {
+	details, ok := m.details[aws.ToString(params.EventDataStore)]
+	if !ok {
+		return nil, errors.New("EventDataStoreNotFoundException")
+	}
+	return details, nil
+}
+
+func newMockTrailsAPI() *mockTrailsAPI {
+	const orgArn = "arn:aws:cloudtrail:us-east-1:123456789012:trail/org"
+	const localArn = "arn:aws:cloudtrail:eu-west-1:123456789012:trail/local"
+	const lakeArn = "arn:aws:cloudtrail:us-east-1:123456789012:eventdatastore/lake"
+	const archiveArn = "arn:aws:cloudtrail:us-east-1:123456789012:eventdatastore/archive"
+	delivered := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	return &mockTrailsAPI{
+		trails: []ctypes.Trail{
+			{
+				Name:                aws.String("org"),
+				TrailARN:            aws.String(orgArn),
+				HomeRegion:          aws.String("us-east-1"),
+				IsMultiRegionTrail:  aws.Bool(true),
+				IsOrganizationTrail: aws.Bool(true),
+				S3BucketName:        aws.String("org-trail-bucket"),
+			},
+			{
+				Name:       aws.String("local"),
+				TrailARN:   aws.String(localArn),
+				HomeRegion: aws.String("eu-west-1"),
+			},
+		},
+		statuses: map[string]*cloudtrail.GetTrailStatusOutput{
+			orgArn:   {IsLogging: aws.Bool(true), LatestDeliveryTime: &delivered},
+			localArn: {IsLogging: aws.Bool(false), LatestDeliveryError: aws.String("AccessDenied")},
+		},
+		selectors: map[string]*cloudtrail.GetEventSelectorsOutput{
+			orgArn: {EventSelectors: []ctypes.EventSelector{{ReadWriteType: ctypes.ReadWriteTypeWriteOnly}}},
+			localArn: {AdvancedEventSelectors: []ctypes.AdvancedEventSelector{{
+				Name: aws.String("S3 writes"),
+				FieldSelectors: []ctypes.AdvancedFieldSelector{
+					{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+					{Field: aws.String("readOnly"), Equals: []string{"false"}},
+				},
+			}}},
+		},
+		// ListEventDataStores leaves out the deprecated fields, only
+		// GetEventDataStore describes the stores
+		stores: [][]ctypes.EventDataStore{
+			{{Name: aws.String("lake"), EventDataStoreArn: aws.String(lakeArn)}},
+			{{Name: aws.String("archive"), EventDataStoreArn: aws.String(archiveArn)}},
+		},
+		details: map[string]*cloudtrail.GetEventDataStoreOutput{
+			lakeArn: {
+				Name:               aws.String("lake"),
+				EventDataStoreArn:  aws.String(lakeArn),
+				Status:             ctypes.EventDataStoreStatusEnabled,
+				MultiRegionEnabled: aws.Bool(true),
+				RetentionPeriod:    aws.Int32(2557),
+				AdvancedEventSelectors: []ctypes.AdvancedEventSelector{{
+					Name:           aws.String("Management events"),
+					FieldSelectors: []ctypes.AdvancedFieldSelector{{Field: aws.String("eventCategory"), Equals: []string{"Management"}}},
+				}},
+			},
+			archiveArn: {
+				Name:              aws.String("archive"),
+				EventDataStoreArn: aws.String(archiveArn),
+				Status:            ctypes.EventDataStoreStatusStoppedIngestion,
+			},
+		},
+	}
+}
+
+func TestDescribeSelectors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		selectors []ctypes.EventSelector
+		advanced  []ctypes.AdvancedEventSelector
+		expected  []string
+	}{
+		{
+			name:      "Default selector",
+			selectors: []ctypes.EventSelector{{}},
+			expected:  []string{"Management events: All"},
+		},
+		{
+			name: "Management exclusions and data resources",
+			selectors: []ctypes.EventSelector{{
+				ReadWriteType:                 ctypes.ReadWriteTypeReadOnly,
+				ExcludeManagementEventSources: []string{"kms.amazonaws.com"},
+				DataResources: []ctypes.DataResource{
+					{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3:::bucket/"}},
+				},
+			}},
+			expected: []string{
+				"Management events: ReadOnly (excluding kms.amazonaws.com)",
+				"Data events: AWS::S3::Object arn:aws:s3:::bucket/ (ReadOnly)",
+			},
+		},
+		{
+			name: "Data events only",
+			selectors: []ctypes.EventSelector{{
+				IncludeManagementEvents: aws.Bool(false),
+				DataResources: []ctypes.DataResource{
+					{Type: aws.String("AWS::Lambda::Function"), Values: []string{"arn:aws:lambda"}},
+				},
+			}},
+			expected: []string{"Data events: AWS::Lambda::Function arn:aws:lambda (All)"},
+		},
+		{
+			name: "Advanced selectors",
+			advanced: []ctypes.AdvancedEventSelector{
+				{
+					Name: aws.String("Management"),
+					FieldSelectors: []ctypes.AdvancedFieldSelector{
+						{Field: aws.String("eventCategory"), Equals: []string{"Management"}},
+					},
+				},
+				{
+					FieldSelectors: []ctypes.AdvancedFieldSelector{
+						{Field: aws.String("resources.type"), Equals: []string{"AWS::S3::Object"}},
+						{Field: aws.String("resources.ARN"), StartsWith: []string{"arn:aws:s3:::a/", "arn:aws:s3:::b/"}, NotEndsWith: []string{".tmp"}},
+					},
+				},
+			},
+			expected: []string{
+				"Management: eventCategory = Management",
+				"resources.type = AWS::S3::Object; resources.ARN starts with arn:aws:s3:::a/, arn:aws:s3:::b/; resources.ARN does not end with .tmp",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			got := describeSelectors(tc.selectors, tc.advanced)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderTrailList(t *testing.T) {
+	api := newMockTrailsAPI()
+	var buf bytes.Buffer
+	if err := renderTrailList(context.Background(), api, &buf, types.CloudTrailCliInput{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"org-trail-bucket", "all regions", "eu-west-1", "lake", "archive", "ENABLED", "2557", "STOPPED_INGESTION"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q:\n%s", expected, output)
+		}
+	}
+	if api.listCalls != 2 {
+		t.Errorf("expected event data stores to be paged twice, got %d calls", api.listCalls)
+	}
+}
+
+func TestRenderTrailListUnreadableStatus(t *testing.T) {
+	api := newMockTrailsAPI()
+	delete(api.statuses, "arn:aws:cloudtrail:us-east-1:123456789012:trail/org")
+
+	var buf bytes.Buffer
+	if err := renderTrailList(context.Background(), api, &buf, types.CloudTrailCliInput{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"unknown", "local", "lake"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRenderTrailStatus(t *testing.T) {
+	testCases := []struct {
+		name        string
+		trail       string
+		unreadable  string
+		contains    []string
+		notContains []string
+		expectError bool
+	}{
+		{
+			name:  "All trails and event data stores",
+			trail: "",
+			contains: []string{
+				"Trail org", "Trail local", "2024-01-15T10:00:00Z", "AccessDenied",
+				"Management events: WriteOnly", "S3 writes: eventCategory = Data; readOnly = false",
+				"Event data store lake", "Management events: eventCategory = Management",
+			},
+		},
+		{
+			name:        "Single trail by name",
+			trail:       "local",
+			contains:    []string{"Trail local", "off"},
+			notContains: []string{"Trail org", "Event data store"},
+		},
+		{
+			name:     "Single trail by ARN",
+			trail:    "arn:aws:cloudtrail:us-east-1:123456789012:trail/org",
+			contains: []string{"Trail org"},
+		},
+		{
+			name:       "Unreadable trail status",
+			trail:      "",
+			unreadable: "arn:aws:cloudtrail:us-east-1:123456789012:trail/org",
+			contains:   []string{"Trail org", "Trail local", "StatusError", "TrailNotFoundException", "Event data store lake"},
+		},
+		{
+			name:        "Unknown trail",
+			trail:       "missing",
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockTrailsAPI()
+			delete(api.statuses, tc.unreadable)
+
+			var buf bytes.Buffer
+			err := renderTrailStatus(context.Background(), api, &buf, tc.trail, types.CloudTrailCliInput{})
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output := buf.String()
+			for _, expected := range tc.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("expected output to contain %q:\n%s", expected, output)
+				}
+			}
+			for _, unexpected := range tc.notContains {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("expected output not to contain %q:\n%s", unexpected, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/tui"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+// buildTUIRows renders events as rows of the interactive list
+func buildTUIRows(events []*types.Event, config types.CloudTrailCliInput) ([]tui.Row, error) {
+	columns, err := resolveColumns(config.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]tui.Row, 0, len(events))
+	for _, e := range events {
+		// Rows keep the original event to pivot on, only the display is redacted
+		redacted, err := redactEvent(config.Redactor, e)
+		if err != nil {
+			return nil, err
+		}
+		detail, err := formatJSON(redacted.Record, false)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render event %s: %w", e.Record.EventId, err)
+		}
+		rows = append(rows, tui.Row{
+			Cells:  buildCells(e, columns, config),
+			Detail: detail,
+			Event:  e,
+		})
+	}
+	return rows, nil
+}
+
+// tuiPivots returns the re-query hotkeys of the interactive list
+func tuiPivots(i types.CloudTrailCliInput, query func(i types.CloudTrailCliInput) ([]tui.Row, error)) []tui.Pivot {
+	pivot := func(key rune, label string, build func(e *types.Event) (types.CloudTrailCliInput, string, error)) tui.Pivot {
+		return tui.Pivot{
+			Key:   key,
+			Label: label,
+			Query: func(row tui.Row) (string, []tui.Row, error) {
+				input, title, err := build(row.Event)
+				if err != nil {
+					return "", nil, err
+				}
+				rows, err := query(input)
+				return title, rows, err
+			},
+		}
+	}
+
+	return []tui.Pivot{
+		pivot('u', "by user", func(e *types.Event) (types.CloudTrailCliInput, string, error) {
+			name := e.Username
+			if name == "" {
+				return i, "", fmt.Errorf("event %s has no user name to pivot on", e.Record.EventId)
+			}
+			input := pivotInput(i)
+			input.UserName = name
+			return input, "user " + i.Redactor.Text(name), nil
+		}),
+		pivot('k', "by access key", func(e *types.Event) (types.CloudTrailCliInput, string, error) {
+			key := e.Record.UserIdentity.AccessKeyId
+			if key == "" {
+				return i, "", fmt.Errorf("event %s has no access key to pivot on", e.Record.EventId)
+			}
+			input := pivotInput(i)
+			input.AccessKeyId = key
+			return input, "access key " + i.Redactor.Text(key), nil
+		}),
+		pivot('i', "by source IP", func(e *types.Event) (types.CloudTrailCliInput, string, error) {
+			ip := e.Record.SourceIPAddress
+			if ip == "" {
+				return i, "", fmt.Errorf("event %s has no source IP to pivot on", e.Record.EventId)
+			}
+			// LookupEvents cannot filter on the source IP, so it is filtered client-side
+			input := pivotInput(i)
+			input.Where = []string{"SourceIPAddress=" + ip}
+			return input, "source IP " + i.Redactor.Text(ip), nil
+		}),
+	}
+}
+
+// tuiHandlerWithLookup allows injection of LookupEvents function for testing
+func tuiHandlerWithLookup(i types.CloudTrailCliInput, lookupFunc LookupEventsFunc) error {
+	if err := validateInput(i); err != nil {
+		return err
+	}
+	if i.EventDataStore != "" {
+		return fmt.Errorf("--tui is not supported with --event-data-store")
+	}
+	if i.Insights {
+		return fmt.Errorf("--tui is not supported with --insights")
+	}
+	if len(i.GroupBy) > 0 {
+		return fmt.Errorf("--tui is not supported with --group-by")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+	defer cancel()
+
+	svc, err := createCloudTrailClient(ctx, i.Region, i.Profile, i.EndpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareTimeRange(&i); err != nil {
+		return err
+	}
+
+	// Each query gets its own timeout as the interface may stay open for long
+	query := func(i types.CloudTrailCliInput) ([]tui.Row, error) {
+		input, err := buildCloudTrailInput(i)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), constants.OperationTimeout)
+		defer cancel()
+
+		events, err := lookupFunc(ctx, svc, input, i.MaxResults)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve CloudTrail events. Please check your permissions and try again")
+		}
+		return buildTUIRows(filterEvents(events, i), i)
+	}
+
+	rows, err := query(i)
+	if err != nil {
+		return err
+	}
+
+	return tui.Run(describeQuery(i), columnNames(i), rows, tuiPivots(i, query))
+}
+
+// describeQuery summarizes the lookup of the initial list
+func describeQuery(i types.CloudTrailCliInput) string {
+	input, err := buildCloudTrailInput(i)
+	if err != nil || len(input.LookupAttributes) == 0 {
+		return "events"
+	}
+	attr := input.LookupAttributes[0]
+	return fmt.Sprintf("%s %s", attr.AttributeKey, i.Redactor.Text(*attr.AttributeValue))
+}
+
+// TUIHandler browses the events in an interactive terminal interface
+func TUIHandler(i types.CloudTrailCliInput) error {
+	return tuiHandlerWithLookup(i, LookupEvents)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guessi/cloudtrail-cli/pkg/tui"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func TestTUIPivots(t *testing.T) {
+	base := types.CloudTrailCliInput{
+		EventName:  "PutObject",
+		ErrorOnly:  true,
+		Where:      []string{"ResourceType=AWS::S3::Bucket"},
+		MaxResults: 100,
+	}
+
+	var queried types.CloudTrailCliInput
+	pivots := tuiPivots(base, func(i types.CloudTrailCliInput) ([]tui.Row, error) {
+		queried = i
+		return nil, nil
+	})
+
+	row := tui.Row{Event: &types.Event{
+		Username: "alice",
+		Record: &types.CloudTrailEvent{
+			EventId:         "event-1",
+			SourceIPAddress: "203.0.113.10",
+			UserIdentity:    types.UserIdentity{AccessKeyId: "ASIAEXAMPLE"},
+		},
+	}}
+
+	testCases := []struct {
+		key      rune
+		title    string
+		expected types.CloudTrailCliInput
+	}{
+		{key: 'u', title: "user alice", expected: types.CloudTrailCliInput{UserName: "alice", MaxResults: 100}},
+		{key: 'k', title: "access key ASIAEXAMPLE", expected: types.CloudTrailCliInput{AccessKeyId: "ASIAEXAMPLE", MaxResults: 100}},
+		{key: 'i', title: "source IP 203.0.113.10", expected: types.CloudTrailCliInput{Where: []string{"SourceIPAddress=203.0.113.10"}, MaxResults: 100}},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(string(tc.key), func(t *testing.T) {
+			var pivot *tui.Pivot
+			for idx := range pivots {
+				if pivots[idx].Key == tc.key {
+					pivot = &pivots[idx]
+				}
+			}
+			if pivot == nil {
+				t.Fatalf("No pivot bound to %q", tc.key)
+			}
+
+			title, _, err := pivot.Query(row)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if title != tc.title {
+				t.Errorf("Expected title %q, got %q", tc.title, title)
+			}
+			if !reflect.DeepEqual(queried, tc.expected) {
+				t.Errorf("Expected query %+v, got %+v", tc.expected, queried)
+			}
+		})
+	}
+
+	// Pivots on missing values fail without querying
+	empty := tui.Row{Event: &types.Event{Record: &types.CloudTrailEvent{EventId: "event-2"}}}
+	for _, pivot := range pivots {
+		if _, _, err := pivot.Query(empty); err == nil {
+			t.Errorf("Expected pivot %q to fail on an event without value", pivot.Key)
+		}
+	}
+}
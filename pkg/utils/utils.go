@@ -61,19 +61,130 @@ func truncateString(truncate bool, input string, maxLength int) string {
 
 // getDisplayUserName extracts a readable username from CloudTrail user identity
 func getDisplayUserName(u types.UserIdentity) string {
+	var name string
 	switch u.Type {
-	case "IAMUser", "WebIdentityUser":
-		return u.UserName
+	case "Root":
+		if u.AccountId == "" {
+			return "root"
+		}
+		return "root@" + u.AccountId
+	case "IAMUser":
+		name = firstNonEmpty(u.UserName, arnResourceName(u.Arn))
 	case "AssumedRole":
-		// Extract role name from ARN (format: arn:aws:sts::account:assumed-role/role-name/session-name)
+		// Extract session name from ARN (format: arn:aws:sts::account:assumed-role/role-name/session-name)
 		parts := strings.Split(u.Arn, "/")
 		if len(parts) >= 3 {
 			return parts[2]
 		}
-		return u.Arn // fallback to full ARN if parsing fails
+		name = u.Arn // fallback to full ARN if parsing fails
+	case "Role":
+		name = arnResourceName(u.Arn)
+	case "FederatedUser":
+		// ARN format: arn:aws:sts::account:federated-user/user-name
+		name = arnResourceName(u.Arn)
+		if name == "" {
+			// principalId format: account:user-name
+			if idx := strings.Index(u.PrincipalId, ":"); idx >= 0 {
+				name = u.PrincipalId[idx+1:]
+			}
+		}
+	case "AWSService":
+		name = u.InvokedBy
+	case "AWSAccount":
+		name = firstNonEmpty(u.AccountId, u.PrincipalId)
+		if name != "" && u.InvokedBy != "" {
+			name += " (" + u.InvokedBy + ")"
+		}
+	case "IdentityCenterUser":
+		if u.OnBehalfOf != nil {
+			name = u.OnBehalfOf.UserId
+		}
+		name = firstNonEmpty(name, u.UserName, u.PrincipalId)
+	case "WebIdentityUser", "SAMLUser", "Directory", "Unknown":
+		name = firstNonEmpty(u.UserName, u.PrincipalId)
+		if name == "" && u.OnBehalfOf != nil {
+			name = u.OnBehalfOf.UserId
+		}
 	default:
+		name = firstNonEmpty(u.UserName, u.Arn)
+	}
+
+	if name == "" {
 		return "-"
 	}
+	return name
+}
+
+// formatIdentityName renders the caller of an event according to --identity-format.
+// Assumed role sessions can be shown by role, session or both; "arn" prefers the
+// full ARN of any identity. Other identities fall back to getDisplayUserName.
+func formatIdentityName(u types.UserIdentity, format string) string {
+	if format == "arn" && u.Arn != "" {
+		return u.Arn
+	}
+	if u.Type == "AssumedRole" {
+		// ARN format: arn:aws:sts::account:assumed-role/role-name/session-name
+		parts := strings.Split(u.Arn, "/")
+		if len(parts) >= 3 {
+			switch format {
+			case "role":
+				return parts[1]
+			case "role/session":
+				return parts[1] + "/" + parts[2]
+			}
+		}
+	}
+	return getDisplayUserName(u)
+}
+
+// arnResourceName returns the last path segment of an ARN resource
+// (e.g. "MyRole" for "arn:aws:iam::123456789012:role/path/MyRole")
+func arnResourceName(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	resource := parts[5]
+	if idx := strings.LastIndex(resource, "/"); idx >= 0 {
+		return resource[idx+1:]
+	}
+	return resource
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// TruncateLength returns the configured truncation length, or the default one
+func TruncateLength(length int) int {
+	if length > 0 {
+		return length
+	}
+	return constants.DefaultTruncateLength
+}
+
+// containsString reports whether a string slice contains the given value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatOptionalBool renders a boolean record field, leaving it blank when absent
+func formatOptionalBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
 }
 
 // getBatchSize returns the appropriate batch size for CloudTrail API pagination
@@ -205,7 +205,80 @@ func TestGetDisplayUserName(t *testing.T) {
 				Type:      "Root",
 				AccountId: "123456789012",
 			},
-			expected: "-",
+			expected: "root@123456789012",
+		},
+		{
+			name: "IAM User without user name",
+			identity: types.UserIdentity{
+				Type: "IAMUser",
+				Arn:  "arn:aws:iam::123456789012:user/team/alice",
+			},
+			expected: "alice",
+		},
+		{
+			name: "Role",
+			identity: types.UserIdentity{
+				Type: "Role",
+				Arn:  "arn:aws:iam::123456789012:role/service-role/MyRole",
+			},
+			expected: "MyRole",
+		},
+		{
+			name: "Federated User",
+			identity: types.UserIdentity{
+				Type: "FederatedUser",
+				Arn:  "arn:aws:sts::123456789012:federated-user/bob",
+			},
+			expected: "bob",
+		},
+		{
+			name: "Federated User from principal id",
+			identity: types.UserIdentity{
+				Type:        "FederatedUser",
+				PrincipalId: "123456789012:bob",
+			},
+			expected: "bob",
+		},
+		{
+			name: "AWS Service",
+			identity: types.UserIdentity{
+				Type:      "AWSService",
+				InvokedBy: "cloudtrail.amazonaws.com",
+			},
+			expected: "cloudtrail.amazonaws.com",
+		},
+		{
+			name: "AWS Account",
+			identity: types.UserIdentity{
+				Type:      "AWSAccount",
+				AccountId: "111122223333",
+				InvokedBy: "ec2.amazonaws.com",
+			},
+			expected: "111122223333 (ec2.amazonaws.com)",
+		},
+		{
+			name: "SAML User",
+			identity: types.UserIdentity{
+				Type:     "SAMLUser",
+				UserName: "alice@example.com",
+			},
+			expected: "alice@example.com",
+		},
+		{
+			name: "Identity Center User",
+			identity: types.UserIdentity{
+				Type:       "IdentityCenterUser",
+				OnBehalfOf: &types.OnBehalfOf{UserId: "94482488-3041-7026-18f3-be45837cd0e4"},
+			},
+			expected: "94482488-3041-7026-18f3-be45837cd0e4",
+		},
+		{
+			name: "Directory",
+			identity: types.UserIdentity{
+				Type:        "Directory",
+				PrincipalId: "d-1234567890/alice",
+			},
+			expected: "d-1234567890/alice",
 		},
 		{
 			name: "Unknown Type",
@@ -235,6 +308,44 @@ func TestGetDisplayUserName(t *testing.T) {
 	}
 }
 
+func TestFormatIdentityName(t *testing.T) {
+	assumedRole := types.UserIdentity{
+		Type: "AssumedRole",
+		Arn:  "arn:aws:sts::123456789012:assumed-role/Admin/alice",
+	}
+	iamUser := types.UserIdentity{
+		Type:     "IAMUser",
+		UserName: "bob",
+		Arn:      "arn:aws:iam::123456789012:user/bob",
+	}
+
+	testCases := []struct {
+		name     string
+		identity types.UserIdentity
+		format   string
+		expected string
+	}{
+		{name: "Default shows session", identity: assumedRole, format: "", expected: "alice"},
+		{name: "Session", identity: assumedRole, format: "session", expected: "alice"},
+		{name: "Role", identity: assumedRole, format: "role", expected: "Admin"},
+		{name: "Role and session", identity: assumedRole, format: "role/session", expected: "Admin/alice"},
+		{name: "ARN", identity: assumedRole, format: "arn", expected: "arn:aws:sts::123456789012:assumed-role/Admin/alice"},
+		{name: "Role format on IAM user", identity: iamUser, format: "role", expected: "bob"},
+		{name: "ARN format on IAM user", identity: iamUser, format: "arn", expected: "arn:aws:iam::123456789012:user/bob"},
+		{name: "ARN format without ARN", identity: types.UserIdentity{Type: "AWSService", InvokedBy: "s3.amazonaws.com"}, format: "arn", expected: "s3.amazonaws.com"},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			got := formatIdentityName(tc.identity, tc.format)
+			if got != tc.expected {
+				t.Errorf("formatIdentityName(%+v, %q) = %q, want %q", tc.identity, tc.format, got, tc.expected)
+			}
+		})
+	}
+}
+
 func TestGetBatchSize(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/guessi/cloudtrail-cli/pkg/constants"
+	"github.com/guessi/cloudtrail-cli/pkg/integrity"
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// validateHandlerWithWriter validates a directory of log files, writing the
+// results to w and the summary to summary
+func validateHandlerWithWriter(i types.CloudTrailCliInput, w, summary io.Writer) error {
+	if i.LogDirectory == "" {
+		return fmt.Errorf("a directory of downloaded log and digest files is required")
+	}
+	if len(i.PublicKeyFiles) == 0 {
+		return fmt.Errorf("--public-key is required to verify digest file signatures")
+	}
+	if i.Output != "" && !containsString(constants.OutputFormats, i.Output) {
+		return fmt.Errorf("invalid --output %q, must be one of: %s", i.Output, strings.Join(constants.OutputFormats, ", "))
+	}
+
+	var keys []integrity.PublicKey
+	for _, path := range i.PublicKeyFiles {
+		loaded, err := integrity.LoadPublicKeys(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, loaded...)
+	}
+
+	report, err := integrity.Validate(i.LogDirectory, keys)
+	if err != nil {
+		return err
+	}
+
+	var rows []table.Row
+	for _, result := range report.Results {
+		rows = append(rows, table.Row{string(result.Status), result.Kind, result.File, result.Detail})
+	}
+	if err := renderRows(w, i.Output, table.Row{"Status", "Type", "File", "Detail"}, displayRows(i, rows)); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(summary, report.Summary())
+	if failures := report.Failures(); failures > 0 {
+		return fmt.Errorf("%d files failed validation", failures)
+	}
+	return nil
+}
+
+// ValidateHandler verifies downloaded log files against their digest chain
+func ValidateHandler(i types.CloudTrailCliInput) error {
+	return validateHandlerWithWriter(i, os.Stdout, os.Stderr)
+}
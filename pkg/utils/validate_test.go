@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/guessi/cloudtrail-cli/pkg/types"
+)
+
+func TestValidateHandler(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)})
+	if err := os.WriteFile(keyFile, keyPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outOfChain := filepath.Join(dir, "123456789012_CloudTrail_us-east-1_20250512T0000Z_a.json.gz")
+	if err := os.WriteFile(outOfChain, []byte(`{"Records":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name            string
+		input           types.CloudTrailCliInput
+		expectedSummary string
+		expectError     bool
+	}{
+		{
+			name:            "Directory without digest files",
+			input:           types.CloudTrailCliInput{LogDirectory: dir, PublicKeyFiles: []string{keyFile}, Output: "csv"},
+			expectedSummary: "digest files: none; log files: 1 out-of-chain\n",
+		},
+		{
+			name:        "Missing directory argument",
+			input:       types.CloudTrailCliInput{PublicKeyFiles: []string{keyFile}},
+			expectError: true,
+		},
+		{
+			name:        "Missing public key",
+			input:       types.CloudTrailCliInput{LogDirectory: dir},
+			expectError: true,
+		},
+		{
+			name:        "Unreadable public key",
+			input:       types.CloudTrailCliInput{LogDirectory: dir, PublicKeyFiles: []string{outOfChain}},
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var out, summary bytes.Buffer
+			err := validateHandlerWithWriter(tc.input, &out, &summary)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if summary.String() != tc.expectedSummary {
+				t.Errorf("summary = %q, want %q", summary.String(), tc.expectedSummary)
+			}
+			if !bytes.Contains(out.Bytes(), []byte("OUT-OF-CHAIN,log,123456789012_CloudTrail_us-east-1_20250512T0000Z_a.json.gz")) {
+				t.Errorf("unexpected output:\n%s", out.String())
+			}
+		})
+	}
+}